@@ -0,0 +1,151 @@
+// Command replay runs the agent offline against a recorded run: the claude CLI
+// is replaced by a replay of its stream-json output and Slack by a fake client
+// that records every post. The recorded Slack output is printed, so it can be
+// compared against a known-good copy after changes to progress rendering,
+// summaries or command handling.
+//
+// Usage:
+//
+//	replay -recording data/runs/<task-id> "<@UBOT> fix the login bug" status transcript
+//
+// The first message is sent as a mention and starts a session; the following
+// ones are sent to its thread, each after the previous run has finished.
+// Messages starting with "/" are sent as slash commands ("/claude-admin").
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/agent"
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/budget"
+	"github.com/toshin/slack-claude-agent/internal/claude"
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+)
+
+const (
+	channel  = "C0000000001"
+	threadTS = "1600000000.000001" // timestamp of the mention that starts the session
+)
+
+func main() {
+	recordingPath := flag.String("recording", "", "run directory or stream-json file (.gz is decompressed) to replay")
+	speed := flag.Float64("speed", 0, "replay speed: 1 = recorded timing, 0 = no delays")
+	repoKeys := flag.String("repo", "example/repo", "comma-separated repositories (owner/name); the session starts in the first")
+	workDir := flag.String("workdir", os.TempDir(), "directory the replayed run pretends to work in")
+	user := flag.String("user", "U0000000001", "Slack user ID sending the messages (an admin)")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long to wait for each message to be handled")
+	retries := flag.Int("retries", 0, "retry transient failures this many times, without waiting")
+	gitHost := flag.String("githost", "", "serve pull requests from the bare repositories <dir>/<owner>/<name>.git (enables prs and pr <number>)")
+	runsPath := flag.String("runs", "", "record the replayed runs in this directory (enables the transcript command)")
+	out := flag.String("o", "", "write the Slack output to this file instead of stdout")
+	verbose := flag.Bool("v", false, "log agent activity to stderr")
+	flag.Parse()
+
+	if *recordingPath == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: replay -recording <run dir or stream file> <mention> [thread message...]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	level := slog.LevelError
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	recording, err := runstore.LoadRecording(*recordingPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load recording: %v\n", err)
+		os.Exit(1)
+	}
+
+	repos, err := domain.ParseRepositories(*repoKeys, "main")
+	if err != nil || len(repos) == 0 {
+		fmt.Fprintf(os.Stderr, "invalid -repo %q: want owner/name[,owner/name...]\n", *repoKeys)
+		os.Exit(2)
+	}
+
+	var runs *runstore.Store
+	if *runsPath != "" {
+		runs, err = runstore.New(*runsPath, runstore.Options{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open run store: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Every repository replays the same recording
+	backends := make(map[string]backend.Backend)
+	for _, repo := range repos {
+		backends[repo.Key()] = claude.NewReplayRunner(claude.ReplayConfig{
+			Open:    recording.Open,
+			Offsets: recording.Offsets,
+			Speed:   *speed,
+			WorkDir: *workDir,
+		}, logger)
+	}
+
+	// Pull requests come from local bare repositories, if given
+	var hosts map[string]githost.Host
+	if *gitHost != "" {
+		local := githost.NewLocal(*gitHost)
+		hosts = make(map[string]githost.Host)
+		for _, repo := range repos {
+			hosts[repo.Key()] = local
+		}
+	}
+
+	fake := slackclient.NewFakeClient()
+	ag := agent.New(agent.Config{
+		SlackClient:  fake,
+		Backends:     backends,
+		Repositories: repos,
+		DefaultRepo:  repos[0],
+		Budgets:      budget.NewTracker(budget.Limits{}),
+		Runs:         runs,
+		Hosts:        hosts,
+		Timeouts:     domain.TimeoutPolicy{Default: 30 * time.Minute},
+		Retries:      domain.RetryPolicy{Max: *retries},
+		Scheduler:    scheduler.New(scheduler.Limits{}),
+		AdminUsers:   []string{*user},
+	}, logger)
+
+	ctx := context.Background()
+	for i, text := range flag.Args() {
+		ts := fmt.Sprintf("1600000000.%06d", i+1)
+		switch {
+		case strings.HasPrefix(text, "/"):
+			command, args, _ := strings.Cut(text, " ")
+			ag.HandleSlashCommand(ctx, command, args, channel, *user, "")
+		case i == 0:
+			ag.HandleMention(ctx, slackclient.Event{Type: "app_mention", User: *user, Text: text, Channel: channel, TS: ts})
+		default:
+			ag.HandleThreadMessage(ctx, slackclient.Event{Type: "message", User: *user, Text: text, Channel: channel, TS: ts, ThreadTS: threadTS})
+		}
+		if !ag.Wait(*timeout) {
+			fmt.Fprintf(os.Stderr, "timed out waiting for message %d (%q)\n", i+1, text)
+			os.Exit(1)
+		}
+	}
+
+	output := fake.Transcript()
+	if *out == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "write output: %v\n", err)
+		os.Exit(1)
+	}
+}
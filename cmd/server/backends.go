@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/claude"
+	"github.com/toshin/slack-claude-agent/internal/config"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
+)
+
+// newBackends creates the agent backend of each repository (see AGENT_BACKEND
+// and AGENT_BACKEND_REPOS). To add a backend, implement backend.Backend and
+// add a case here. Checkouts are located by boot; hosts point gh and glab at
+// each repository's server; hooksPath holds the hooks of the runs' commits.
+func newBackends(cfg *config.Config, boot *workspace.Bootstrapper, hosts map[string]githost.Host, hooksPath string, logger *slog.Logger) (map[string]backend.Backend, error) {
+	var recording *runstore.Recording // loaded once, shared by all replay backends
+
+	backends := make(map[string]backend.Backend)
+	for _, repo := range cfg.Repositories {
+		runner := claude.NewRunner(claude.Config{
+			ClaudePath:    cfg.ClaudePath,
+			WorkspacePath: cfg.WorkspacePath,
+			WorkDir:       boot.WorkDir(repo),
+			GitHubOwner:   repo.Owner,
+			GitHubRepo:    repo.Name,
+			DefaultBranch: repo.DefaultBranch,
+			Target:        repo.Target,
+			GitHost:       cfg.GitHostFor(repo.Key()),
+			Env:           append(hosts[repo.Key()].Env(), cfg.SigningFor(repo.Key()).Env()...),
+			GitConfig:     cfg.SigningFor(repo.Key()).GitConfig(),
+			HooksPath:     hooksPath,
+			AuthorName:    cfg.AuthorName,
+			AuthorEmail:   cfg.AuthorEmail,
+			CoAuthorName:  cfg.CoAuthorName,
+			CoAuthorEmail: cfg.CoAuthorEmail,
+			Attribution:   cfg.CommitAttribution,
+		}, logger)
+
+		switch name := cfg.BackendFor(repo.Key()); name {
+		case claude.BackendCLI:
+			backends[repo.Key()] = runner
+
+		case claude.BackendReplay:
+			if recording == nil {
+				if cfg.ReplayPath == "" {
+					return nil, fmt.Errorf("%s: backend %q requires REPLAY_PATH", repo.Key(), name)
+				}
+				var err error
+				if recording, err = runstore.LoadRecording(cfg.ReplayPath); err != nil {
+					return nil, fmt.Errorf("load replay recording: %w", err)
+				}
+			}
+			logger.Warn("replay backend: claude is not run, tasks replay a recording", "repository", repo.Key(), "path", cfg.ReplayPath, "speed", cfg.ReplaySpeed)
+			backends[repo.Key()] = claude.NewReplayRunner(claude.ReplayConfig{
+				Open:    recording.Open,
+				Offsets: recording.Offsets,
+				Speed:   cfg.ReplaySpeed,
+				WorkDir: runner.WorkDir(),
+			}, logger)
+
+		default:
+			return nil, fmt.Errorf("%s: unknown backend %q", repo.Key(), name)
+		}
+		logger.Info("initialized backend for repository", "repository", repo.Key(), "backend", backends[repo.Key()].Name(), "branch", repo.DefaultBranch)
+	}
+	return backends, nil
+}
+
+// usesBackend reports whether any repository uses the named backend.
+func usesBackend(backends map[string]backend.Backend, name string) bool {
+	for _, b := range backends {
+		if b.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/toshin/slack-claude-agent/internal/config"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+)
+
+// newHosts creates the git host of each repository (see GIT_HOST,
+// GIT_HOST_REPOS and GIT_HOST_URL_REPOS). Repositories on the same server
+// share one instance.
+func newHosts(cfg *config.Config) (map[string]githost.Host, error) {
+	shared := make(map[string]githost.Host) // key: host name and URL
+	hosts := make(map[string]githost.Host)
+	for _, repo := range cfg.Repositories {
+		name := cfg.GitHostFor(repo.Key())
+		baseURL := cfg.GitHostURLFor(repo.Key())
+		if h, ok := shared[name+" "+baseURL]; ok {
+			hosts[repo.Key()] = h
+			continue
+		}
+
+		var h githost.Host
+		switch name {
+		case githost.HostGitHub:
+			h = githost.NewGitHub("gh", baseURL)
+		case githost.HostGitLab:
+			h = githost.NewGitLab(baseURL, cfg.GitLabToken)
+		case githost.HostGitea:
+			if baseURL == "" {
+				return nil, fmt.Errorf("%s: git host %q requires GIT_HOST_URL", repo.Key(), name)
+			}
+			h = githost.NewGitea(baseURL, cfg.GiteaToken)
+		case githost.HostLocal:
+			if cfg.GitHostLocalPath == "" {
+				return nil, fmt.Errorf("%s: git host %q requires GIT_HOST_LOCAL_PATH", repo.Key(), name)
+			}
+			h = githost.NewLocal(cfg.GitHostLocalPath)
+		default:
+			return nil, fmt.Errorf("%s: unknown git host %q", repo.Key(), name)
+		}
+		shared[name+" "+baseURL] = h
+		hosts[repo.Key()] = h
+	}
+	return hosts, nil
+}
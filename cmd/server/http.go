@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
+)
+
+// readinessChecker verifies everything a task needs is available.
+type readinessChecker struct {
+	handler    *slackclient.Handler
+	claudePath string // empty skips the binary check (no repository uses the CLI)
+	backends   map[string]backend.Backend
+	bootstrap  *workspace.Bootstrapper
+}
+
+// check runs all readiness checks and returns a failure message per failing check.
+func (rc *readinessChecker) check() map[string]string {
+	failures := make(map[string]string)
+
+	if !rc.handler.Connected() {
+		failures["socket"] = "socket mode is not connected"
+	}
+
+	if rc.claudePath != "" {
+		if _, err := exec.LookPath(rc.claudePath); err != nil {
+			failures["claude"] = fmt.Sprintf("claude binary not found: %s", err)
+		}
+	}
+
+	for key, err := range rc.bootstrap.Status() {
+		if err != nil {
+			failures["repository:"+key] = err.Error()
+		}
+	}
+
+	for key, b := range rc.backends {
+		info, err := os.Stat(b.WorkDir())
+		if err != nil {
+			failures["workspace:"+key] = err.Error()
+		} else if !info.IsDir() {
+			failures["workspace:"+key] = fmt.Sprintf("%s is not a directory", b.WorkDir())
+		}
+	}
+
+	return failures
+}
+
+func newHTTPMux(rc *readinessChecker) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Liveness: the process is up and serving HTTP
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+
+	// Readiness: able to receive events and run claude
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		failures := rc.check()
+		status := http.StatusOK
+		if len(failures) > 0 {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"ready":    len(failures) == 0,
+			"failures": failures,
+		})
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.Default.WritePrometheus(w)
+	})
+
+	return mux
+}
+
+// startHTTPServer serves mux on addr until ctx is cancelled.
+func startHTTPServer(ctx context.Context, addr string, mux http.Handler, logger *slog.Logger) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		logger.Info("starting http server", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("http server exited", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+}
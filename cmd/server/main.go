@@ -6,11 +6,23 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/toshin/slack-claude-agent/internal/agent"
+	"github.com/toshin/slack-claude-agent/internal/budget"
 	"github.com/toshin/slack-claude-agent/internal/claude"
 	"github.com/toshin/slack-claude-agent/internal/config"
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/gitenv"
+	"github.com/toshin/slack-claude-agent/internal/identity"
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	"github.com/toshin/slack-claude-agent/internal/signing"
 	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
+	"github.com/toshin/slack-claude-agent/internal/webui"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
 )
 
 func main() {
@@ -25,42 +37,212 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Tracing (no-op unless TRACING_EXPORTER is set)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("failed to flush traces", "error", err)
+		}
+	}()
+
 	// Create Socket Mode handler (also creates the Slack API client)
 	handler := slackclient.NewHandler(cfg.SlackAppToken, cfg.SlackBotToken, nil)
 	sc := slackclient.NewClient(handler.APIClient())
 
-	// Create Claude runners for each repository
-	runners := make(map[string]*claude.Runner)
+	// Git host (GitHub by default) of each repository, for clones and pull requests
+	hosts, err := newHosts(cfg)
+	if err != nil {
+		logger.Error("failed to create git hosts", "error", err)
+		os.Exit(1)
+	}
+
+	// Clone missing repositories and check existing checkouts; a repository
+	// that fails is reported by /readyz and retried when a task needs it
+	boot := workspace.NewBootstrapper(workspace.Layout{
+		Root:  cfg.WorkspacePath,
+		Paths: cfg.WorkspacePaths,
+	}, workspace.BootstrapOptions{
+		Clone:    cfg.WorkspaceClone,
+		CloneURL: func(repo *domain.Repository) string { return hosts[repo.Key()].CloneURL(repo) },
+		Env:      func(repo *domain.Repository) []string { return hosts[repo.Key()].Env() },
+	}, logger)
+	bootstrapRepositories(context.Background(), boot, cfg.Repositories, cfg.WorkspaceMigrate, logger)
+
+	// Hooks of the agent's commits (core.hooksPath), adding the Co-Authored-By trailer
+	hooksPath, err := gitenv.InstallHooks(cfg.CommitHooksPath)
+	if err != nil {
+		logger.Error("failed to install git hooks", "error", err)
+		os.Exit(1)
+	}
+
+	// Create the agent backend (claude CLI by default) of each repository
+	backends, err := newBackends(cfg, boot, hosts, hooksPath, logger)
+	if err != nil {
+		logger.Error("failed to create backends", "error", err)
+		os.Exit(1)
+	}
+
+	// Create agent and wire it into the handler
+	// Usage ledger (also seeds today's budget spending so restarts don't reset it)
+	budgets := budget.NewTracker(cfg.Budget)
+	var usage *ledger.Ledger
+	if cfg.UsageLedgerPath != "" {
+		usage, err = ledger.New(cfg.UsageLedgerPath)
+		if err != nil {
+			logger.Error("failed to open usage ledger", "error", err)
+			os.Exit(1)
+		}
+		now := time.Now()
+		today, err := usage.Since(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+		if err != nil {
+			logger.Warn("failed to read usage ledger", "error", err)
+		}
+		for _, e := range today {
+			budgets.Record(e.User, append([]string{e.Repository}, e.Related...), e.Cost)
+		}
+	}
+
+	// Run recordings (transcripts and diffs) for the web UI
+	var runs *runstore.Store
+	if cfg.RunsPath != "" {
+		runs, err = runstore.New(cfg.RunsPath, runstore.Options{
+			MaxBytes:  cfg.RunsMaxBytes,
+			Retention: cfg.RunsRetention,
+		})
+		if err != nil {
+			logger.Error("failed to open run store", "error", err)
+			os.Exit(1)
+		}
+	}
+	webUIEnabled := cfg.HTTPAddr != "" && cfg.WebUIToken != "" && runs != nil
+	webBaseURL := ""
+	if webUIEnabled {
+		webBaseURL = cfg.WebUIBaseURL
+	}
+
+	// Fetch and reset checkouts before each run
+	var prep *workspace.Preparer
+	if cfg.WorkspacePrepare {
+		prep = workspace.New(workspace.Options{
+			Dirty:   workspace.DirtyPolicy(cfg.WorkspaceDirty),
+			Exclude: []string{agent.InputDirName},
+		}, logger)
+	}
+
+	// Git identities of Slack users, for per-user commit attribution
+	var identities *identity.Directory
+	if cfg.CommitIdentitiesPath != "" || cfg.CommitIdentityFromSlack {
+		opts := identity.Options{Path: cfg.CommitIdentitiesPath}
+		if cfg.CommitIdentityFromSlack {
+			opts.Profiles = sc
+		}
+		identities, err = identity.New(opts, logger)
+		if err != nil {
+			logger.Error("failed to load commit identities", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Commit signing of each repository, checked before implementation runs
+	signingConfigs := make(map[string]signing.Config)
 	for _, repo := range cfg.Repositories {
-		runnerCfg := claude.Config{
-			ClaudePath:    cfg.ClaudePath,
-			WorkspacePath: cfg.WorkspacePath,
-			GitHubOwner:   repo.Owner,
-			GitHubRepo:    repo.Name,
-			DefaultBranch: repo.DefaultBranch,
-			AuthorName:    cfg.AuthorName,
-			AuthorEmail:   cfg.AuthorEmail,
-			CoAuthorName:  cfg.CoAuthorName,
-			CoAuthorEmail: cfg.CoAuthorEmail,
-			MaxConcurrent: cfg.MaxConcurrent,
+		if sign := cfg.SigningFor(repo.Key()); sign.Enabled() {
+			signingConfigs[repo.Key()] = sign
 		}
-		runners[repo.Key()] = claude.NewRunner(runnerCfg, logger)
-		logger.Info("initialized runner for repository", "repository", repo.Key(), "branch", repo.DefaultBranch)
 	}
 
-	// Create agent and wire it into the handler
-	ag := agent.New(sc, runners, cfg.Repositories, cfg.DefaultRepository, logger)
+	ag := agent.New(agent.Config{
+		SlackClient:  sc,
+		Backends:     backends,
+		Repositories: cfg.Repositories,
+		DefaultRepo:  cfg.DefaultRepository,
+		Budgets:      budgets,
+		Ledger:       usage,
+		Runs:         runs,
+		Workspace:    prep,
+		Bootstrap:    boot,
+		Hosts:        hosts,
+		Identities:   identities,
+		Signing:      signingConfigs,
+		WebBaseURL:   webBaseURL,
+		Timeouts:     cfg.Timeouts,
+		Models:       cfg.Models,
+		Retries:      cfg.Retries,
+		Scheduler:    scheduler.New(cfg.Scheduler),
+		Priorities:   cfg.Priorities,
+		AdminUsers:   cfg.AdminUsers,
+
+		InterruptedPath: cfg.InterruptedTasksPath,
+	}, logger)
 	handler.SetMentionHandler(ag)
 
+	// Offer to resume tasks interrupted by the previous shutdown
+	ag.RestoreInterrupted()
+
+	// Optional health/readiness/metrics endpoint; kept up while tasks drain on shutdown
+	httpCtx, stopHTTP := context.WithCancel(context.Background())
+	defer stopHTTP()
+	if cfg.HTTPAddr != "" {
+		rc := &readinessChecker{handler: handler, backends: backends, bootstrap: boot}
+		if usesBackend(backends, claude.BackendCLI) {
+			rc.claudePath = cfg.ClaudePath
+		}
+		mux := newHTTPMux(rc)
+		if webUIEnabled {
+			ui, err := webui.New(webui.Config{Token: cfg.WebUIToken, Runs: runs, Sessions: ag.Sessions}, logger)
+			if err != nil {
+				logger.Error("failed to load web ui", "error", err)
+				os.Exit(1)
+			}
+			ui.Register(mux)
+		}
+		startHTTPServer(httpCtx, cfg.HTTPAddr, mux, logger)
+	}
+
 	// Run Socket Mode (blocks until context is cancelled)
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	if runs != nil {
+		go runs.PruneLoop(ctx, time.Hour, logger)
+	}
+
 	logger.Info("starting slack-claude-agent")
-	if err := handler.Run(ctx); err != nil {
+	if err := handler.Run(ctx); err != nil && ctx.Err() == nil {
 		logger.Error("handler exited", "error", err)
 		os.Exit(1)
 	}
 
+	// Drain in-flight runs before exiting
+	logger.Info("shutdown signal received, draining tasks", "grace_period", cfg.ShutdownGracePeriod.String())
+	ag.Shutdown(cfg.ShutdownGracePeriod)
+
 	logger.Info("shutdown complete")
 }
+
+// bootstrapRepositories makes sure each repository has a checkout (moving flat
+// checkouts to owner-qualified paths first if migrate is set) and logs which
+// ones are ready.
+func bootstrapRepositories(ctx context.Context, boot *workspace.Bootstrapper, repos []*domain.Repository, migrate bool, logger *slog.Logger) {
+	ready := 0
+	for _, repo := range repos {
+		if migrate {
+			if err := boot.Migrate(repo); err != nil {
+				logger.Warn("failed to migrate checkout", "repository", repo.Key(), "error", err)
+			}
+		}
+		if err := boot.Ensure(ctx, repo); err != nil {
+			logger.Error("repository is not ready", "repository", repo.Key(), "dir", boot.Dir(repo), "error", err)
+			continue
+		}
+		ready++
+		logger.Info("repository is ready", "repository", repo.Key(), "dir", boot.Dir(repo))
+	}
+	logger.Info("workspace bootstrap finished", "ready", ready, "total", len(repos))
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+)
+
+// actionStopTask is the action ID of the stop buttons on the status dashboard.
+const actionStopTask = "admin_stop_task"
+
+// maxStatusSessions caps the idle sessions listed on the dashboard.
+const maxStatusSessions = 20
+
+func (a *Agent) isAdmin(user string) bool {
+	return a.admins[user]
+}
+
+// handleStatus posts the status dashboard to a channel or thread.
+func (a *Agent) handleStatus(ctx context.Context, channel, threadTS, user string) {
+	if !a.isAdmin(user) {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, ":no_entry: このコマンドは管理者のみ使用できます。")
+		return
+	}
+
+	text, sections := a.buildStatus()
+	if err := a.slackClient.PostThreadSections(ctx, channel, threadTS, text, sections); err != nil {
+		a.logger.Error("failed to post status", "error", err)
+	}
+}
+
+// handleAdminSlash posts the status dashboard for /claude-admin, visible only to the caller.
+func (a *Agent) handleAdminSlash(ctx context.Context, channel, user string) {
+	if !a.isAdmin(user) {
+		a.slackClient.PostEphemeral(ctx, channel, user, ":no_entry: このコマンドは管理者のみ使用できます。")
+		return
+	}
+
+	text, sections := a.buildStatus()
+	if err := a.slackClient.PostEphemeralSections(ctx, channel, user, text, sections); err != nil {
+		a.logger.Error("failed to post status", "error", err)
+	}
+}
+
+// HandleBlockAction handles button clicks on messages posted by the agent.
+func (a *Agent) HandleBlockAction(ctx context.Context, action slackclient.BlockAction) {
+	switch action.ActionID {
+	case actionStopTask:
+		a.handleAdminStop(ctx, action)
+	default:
+		a.logger.Warn("unknown block action", "action_id", action.ActionID)
+	}
+}
+
+// handleAdminStop cancels the task whose ID is in the button value.
+func (a *Agent) handleAdminStop(ctx context.Context, action slackclient.BlockAction) {
+	if !a.isAdmin(action.User) {
+		a.slackClient.PostEphemeral(ctx, action.Channel, action.User, ":no_entry: タスクの停止は管理者のみ実行できます。")
+		return
+	}
+
+	a.tasksMu.Lock()
+	task, ok := a.tasks[action.Value]
+	a.tasksMu.Unlock()
+	if !ok {
+		a.slackClient.PostEphemeral(ctx, action.Channel, action.User, ":information_source: このタスクは既に終了しています。")
+		return
+	}
+
+	a.logger.Info("task stopped by admin", "task_id", task.ID, "admin", action.User, "thread", task.Session.ThreadTS)
+	task.Cancel()
+	a.updateMessage(ctx, task.Session, fmt.Sprintf(":octagonal_sign: 管理者 <@%s> がタスクを停止しました。", action.User))
+	a.slackClient.PostEphemeral(ctx, action.Channel, action.User,
+		fmt.Sprintf(":white_check_mark: <@%s> のタスク（%s）を停止しました。", task.Request.User, task.Repository))
+}
+
+// buildStatus renders the dashboard: overview, slot usage per repository,
+// one section per task with a stop button, and idle sessions.
+func (a *Agent) buildStatus() (string, []slackclient.Section) {
+	stats := a.scheduler.Stats()
+	tasks := a.activeTasks()
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].StartedAt.Before(tasks[j].StartedAt) })
+
+	sessions := a.Sessions()
+
+	overview := fmt.Sprintf(":satellite: *ステータス*\n稼働時間: %s  |  本日のコスト: $%.2f  |  アクティブセッション: %d\n実行中: %d/%d  |  待機中: %d",
+		formatDuration(time.Since(a.startedAt)), a.budgets.SpentToday(), len(sessions),
+		stats.Running, stats.Limits.Global, stats.Queued)
+	sections := []slackclient.Section{{Text: overview}}
+
+	var repoLines strings.Builder
+	repoLines.WriteString("*リポジトリ別:*\n")
+	seen := make(map[string]bool)
+	for _, repo := range a.repositories {
+		// Targets of a monorepo share the slots of its checkout
+		if seen[repo.RepoKey()] {
+			continue
+		}
+		seen[repo.RepoKey()] = true
+		rs := stats.Repos[repo.RepoKey()]
+		limit := "-"
+		if stats.Limits.PerRepo > 0 {
+			limit = fmt.Sprintf("%d", stats.Limits.PerRepo)
+		}
+		repoLines.WriteString(fmt.Sprintf("• %s: 実行中 %d/%s  |  待機中 %d\n", repo.RepoKey(), rs.Running, limit, rs.Queued))
+	}
+	sections = append(sections, slackclient.Section{Text: strings.TrimRight(repoLines.String(), "\n")})
+
+	if len(tasks) == 0 {
+		sections = append(sections, slackclient.Section{Text: "実行中のタスクはありません。"})
+	}
+	for _, t := range tasks {
+		state := ":arrow_forward: 実行中"
+		if t.Queued() {
+			state = ":hourglass_flowing_sand: 待機中"
+		}
+		line := fmt.Sprintf("%s  <@%s>  |  %s  |  %s  |  経過 %s\n<#%s> スレッド %s",
+			state, t.Request.User, t.Repository, t.Mode.String(), formatDuration(time.Since(t.StartedAt)),
+			t.Session.Channel, t.Session.ThreadTS)
+		if tool := t.CurrentTool(); tool != "" {
+			line += fmt.Sprintf("\n:wrench: %s", tool)
+		}
+		sections = append(sections, slackclient.Section{
+			Text:   line,
+			Button: &slackclient.Button{ActionID: actionStopTask, Value: t.ID, Text: "停止", Danger: true},
+		})
+	}
+
+	if len(sessions) > 0 {
+		var sb strings.Builder
+		sb.WriteString("*セッション:*\n")
+		for i, s := range sessions {
+			if i == maxStatusSessions {
+				sb.WriteString(fmt.Sprintf("…ほか %d 件\n", len(sessions)-maxStatusSessions))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("• <#%s> %s  |  %s  |  %s  |  最終操作 %s前\n",
+				s.Channel, s.ThreadTS, s.Repository, s.Mode.String(), formatDuration(time.Since(s.LastActivity))))
+		}
+		sections = append(sections, slackclient.Section{Text: strings.TrimRight(sb.String(), "\n")})
+	}
+
+	fallback := fmt.Sprintf("ステータス: 実行中 %d / 待機中 %d", stats.Running, stats.Queued)
+	return fallback, sections
+}
+
+// Sessions returns the active sessions, most recently used first.
+func (a *Agent) Sessions() []SessionInfo {
+	a.mu.RLock()
+	sessions := make([]SessionInfo, 0, len(a.sessions))
+	for _, s := range a.sessions {
+		if s.Active() {
+			sessions = append(sessions, sessionInfo(s))
+		}
+	}
+	a.mu.RUnlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastActivity.After(sessions[j].LastActivity) })
+	return sessions
+}
+
+// SessionInfo is a snapshot of a session for dashboards.
+type SessionInfo struct {
+	Channel      string
+	ThreadTS     string
+	Repository   string
+	Mode         domain.AgentMode
+	Running      bool
+	LastActivity time.Time
+}
+
+func sessionInfo(s *domain.Session) SessionInfo {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	snap := SessionInfo{
+		Channel:      s.Channel,
+		ThreadTS:     s.ThreadTS,
+		Mode:         s.Mode,
+		Running:      s.IsRunning,
+		LastActivity: s.LastActivity,
+	}
+	if s.Repository != nil {
+		snap.Repository = s.Repository.Key()
+	}
+	return snap
+}
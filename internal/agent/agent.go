@@ -2,17 +2,33 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"os/exec"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/toshin/slack-claude-agent/internal/claude"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/budget"
 	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+	"github.com/toshin/slack-claude-agent/internal/identity"
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	"github.com/toshin/slack-claude-agent/internal/signing"
 	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
 )
 
 var botMentionRe = regexp.MustCompile(`<@U[A-Z0-9]+>`)
@@ -20,26 +36,123 @@ var botMentionRe = regexp.MustCompile(`<@U[A-Z0-9]+>`)
 type Agent struct {
 	mu            sync.RWMutex
 	sessions      map[string]*domain.Session    // key: threadTS
-	slackClient   *slackclient.Client
-	claudeRunner  *claude.Runner                // deprecated: for backward compatibility
-	runners       map[string]*claude.Runner     // key: repository.Key()
+	slackClient   SlackClient
+	backends      map[string]backend.Backend    // key: repository.Key()
 	repositories  []*domain.Repository
 	defaultRepo   *domain.Repository
+	budgets       *budget.Tracker
+	ledger        *ledger.Ledger                // nil disables usage accounting
+	runs          *runstore.Store               // nil disables run recording
+	workspace     *workspace.Preparer           // nil disables workspace preparation
+	bootstrap     *workspace.Bootstrapper       // nil disables cloning missing checkouts
+	hosts         map[string]githost.Host       // key: repository.Key(); missing disables PR commands
+	identities    *identity.Directory           // nil attributes all commits to the bot
+	signing       map[string]signing.Config     // key: repository.Key(); missing means unsigned
+	webBaseURL    string                        // public URL of the web UI, for links in summaries
+	timeouts      domain.TimeoutPolicy
+	models        domain.ModelPolicy
+	retries       domain.RetryPolicy
+	scheduler     *scheduler.Scheduler          // shared by all runners
+	priorities    map[domain.AgentMode]int
+	admins        map[string]bool // Slack user IDs allowed to use admin commands
+	startedAt     time.Time
 	logger        *slog.Logger
+
+	// In-flight runs and shutdown coordination
+	baseCtx         context.Context // parent of every run; cancelled when shutdown gives up waiting
+	baseCancel      context.CancelFunc
+	tasksMu         sync.Mutex
+	tasks           map[string]*activeTask     // key: task ID
+	draining        bool                       // no new tasks are accepted
+	inflight        sync.WaitGroup
+	started         sync.WaitGroup             // runs started by startRun, see Wait
+	interrupted     map[string]interruptedTask // key: task ID
+	interruptedPath string
+	checkouts       checkoutLocks // serializes workspace preparation
+}
+
+// SlackClient is the part of the Slack Web API the agent uses. It is
+// implemented by *slackclient.Client and, for offline replays, by
+// *slackclient.FakeClient.
+type SlackClient interface {
+	AddReaction(ctx context.Context, channel, timestamp, emoji string) error
+	PostMessage(ctx context.Context, channel, text string) error
+	PostMessageReturningTS(ctx context.Context, channel, text string) (string, error)
+	PostThreadMessage(ctx context.Context, channel, threadTS, text string) error
+	PostThreadMessageReturningTS(ctx context.Context, channel, threadTS, text string) (string, error)
+	UpdateThreadMessage(ctx context.Context, channel, messageTS, text string) error
+	UploadFile(ctx context.Context, channel, threadTS, filename, title, content string) error
+	PostThreadSections(ctx context.Context, channel, threadTS, text string, sections []slackclient.Section) error
+	PostEphemeralSections(ctx context.Context, channel, user, text string, sections []slackclient.Section) error
+	PostEphemeral(ctx context.Context, channel, user, text string) error
+	DownloadFile(ctx context.Context, url string, w io.Writer) error
+}
+
+// Config holds the dependencies and settings of an Agent.
+type Config struct {
+	SlackClient  SlackClient
+	Backends     map[string]backend.Backend // key: repository.Key()
+	Repositories []*domain.Repository
+	DefaultRepo  *domain.Repository
+	Budgets      *budget.Tracker
+	Ledger       *ledger.Ledger            // nil disables usage accounting
+	Runs         *runstore.Store           // nil disables run recording
+	Workspace    *workspace.Preparer       // nil disables workspace preparation before runs
+	Bootstrap    *workspace.Bootstrapper   // nil disables cloning missing checkouts before runs
+	Hosts        map[string]githost.Host   // key: repository.Key()
+	Identities   *identity.Directory       // nil attributes all commits to the bot
+	Signing      map[string]signing.Config // key: repository.Key(); checked before implementation runs
+	WebBaseURL   string                    // public URL of the web UI, for links in summaries
+	Timeouts     domain.TimeoutPolicy
+	Models       domain.ModelPolicy
+	Retries      domain.RetryPolicy
+	Scheduler    *scheduler.Scheduler
+	Priorities   map[domain.AgentMode]int // scheduling priority per mode (higher first)
+	AdminUsers   []string                 // Slack user IDs allowed to use admin commands
+
+	// InterruptedPath is where tasks interrupted by shutdown are saved for resumption.
+	InterruptedPath string
 }
 
-func New(sc *slackclient.Client, runners map[string]*claude.Runner, repos []*domain.Repository, defaultRepo *domain.Repository, logger *slog.Logger) *Agent {
-	return &Agent{
+func New(cfg Config, logger *slog.Logger) *Agent {
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	a := &Agent{
 		sessions:     make(map[string]*domain.Session),
-		slackClient:  sc,
-		runners:      runners,
-		repositories: repos,
-		defaultRepo:  defaultRepo,
+		slackClient:  cfg.SlackClient,
+		backends:     cfg.Backends,
+		repositories: cfg.Repositories,
+		defaultRepo:  cfg.DefaultRepo,
+		budgets:      cfg.Budgets,
+		ledger:       cfg.Ledger,
+		runs:         cfg.Runs,
+		workspace:    cfg.Workspace,
+		bootstrap:    cfg.Bootstrap,
+		hosts:        cfg.Hosts,
+		identities:   cfg.Identities,
+		signing:      cfg.Signing,
+		webBaseURL:   cfg.WebBaseURL,
+		timeouts:     cfg.Timeouts,
+		models:       cfg.Models,
+		retries:      cfg.Retries,
+		scheduler:    cfg.Scheduler,
+		priorities:   cfg.Priorities,
+		admins:       make(map[string]bool),
+		startedAt:    time.Now(),
 		logger:       logger,
+
+		baseCtx:         baseCtx,
+		baseCancel:      baseCancel,
+		tasks:           make(map[string]*activeTask),
+		interrupted:     make(map[string]interruptedTask),
+		interruptedPath: cfg.InterruptedPath,
+	}
+	for _, u := range cfg.AdminUsers {
+		a.admins[u] = true
 	}
+	return a
 }
 
-func (a *Agent) HandleThreadMessage(event slackclient.Event) {
+func (a *Agent) HandleThreadMessage(ctx context.Context, event slackclient.Event) {
 	// Only process messages in active sessions
 	threadTS := event.ThreadTS
 	if threadTS == "" {
@@ -65,43 +178,60 @@ func (a *Agent) HandleThreadMessage(event slackclient.Event) {
 
 	// Detect commands
 	cmd := domain.DetectCommand(instruction)
+	ctx, span := startDispatchSpan(ctx, cmd, threadTS)
+	defer span.End()
 
 	// Handle commands
 	switch cmd {
 	case domain.CommandStop:
-		a.stopExecution(session)
+		a.stopExecution(ctx, session)
 		return
 	case domain.CommandEnd:
-		a.endSession(session, event.User)
+		a.endSession(ctx, session, event.User)
 		return
 	case domain.CommandReview:
 		session.SetMode(domain.ModeReview)
-		a.slackClient.PostThreadMessage(event.Channel, threadTS,
+		a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 			fmt.Sprintf(":mag: レビューモードに切り替えました"))
 		return
 	case domain.CommandImplement:
 		session.SetMode(domain.ModeImplementation)
-		a.slackClient.PostThreadMessage(event.Channel, threadTS,
+		a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 			fmt.Sprintf(":hammer_and_wrench: 実装モードに切り替えました"))
 		return
 	case domain.CommandSwitch:
-		a.handleSwitchRepo(session, instruction)
+		a.handleSwitchRepo(ctx, session, instruction)
 		return
 	case domain.CommandRepos:
-		a.handleListRepos(session)
+		a.handleListRepos(ctx, session)
 		return
 	case domain.CommandSync:
 		session.SetExecutionMode(domain.ExecutionSync)
-		a.slackClient.PostThreadMessage(event.Channel, threadTS,
+		a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 			fmt.Sprintf(":arrow_forward: 順次実行モードに切り替えました（タスクを1つずつ順番に実行）"))
 		return
 	case domain.CommandAsync:
 		session.SetExecutionMode(domain.ExecutionAsync)
-		a.slackClient.PostThreadMessage(event.Channel, threadTS,
+		a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 			fmt.Sprintf(":fast_forward: 並列実行モードに切り替えました（複数タスクを同時実行）"))
 		return
 	case domain.CommandPRs:
-		a.handleListPRs(session)
+		a.handleListPRs(ctx, session)
+		return
+	case domain.CommandPRStatus:
+		a.handlePRStatus(ctx, session.Channel, session.ThreadTS, domain.ExtractPRNumber(instruction))
+		return
+	case domain.CommandUsage:
+		a.handleUsage(ctx, session.Channel, session.ThreadTS, domain.ExtractUsageDays(instruction, defaultUsageDays))
+		return
+	case domain.CommandResume:
+		a.handleResume(ctx, session, event.User)
+		return
+	case domain.CommandStatus:
+		a.handleStatus(ctx, event.Channel, threadTS, event.User)
+		return
+	case domain.CommandTranscript:
+		a.handleTranscript(ctx, event.Channel, threadTS)
 		return
 	}
 
@@ -109,20 +239,20 @@ func (a *Agent) HandleThreadMessage(event slackclient.Event) {
 	if session.Running() {
 		execMode := session.GetExecutionMode()
 		if execMode == domain.ExecutionSync {
-			a.slackClient.PostThreadMessage(event.Channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 				":hourglass: 順次実行モード：現在実行中です。完了後にもう一度メッセージを送信してください。")
 		} else {
-			a.slackClient.PostThreadMessage(event.Channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, event.Channel, threadTS,
 				":warning: 並列実行モード：既に実行中です。新しいタスクを開始する場合は別のスレッドを使用してください。")
 		}
 		return
 	}
 
 	// Continue session
-	a.continueSession(session, instruction)
+	a.continueSession(ctx, session, taskRequest{User: event.User, Prompt: instruction, Files: event.Files})
 }
 
-func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL string) {
+func (a *Agent) HandleSlashCommand(ctx context.Context, command, text, channel, user, responseURL string) {
 	// Map slash command to mode and instruction
 	var mode domain.AgentMode
 	var instruction string
@@ -136,15 +266,21 @@ func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL str
 		instruction = text
 	case "/claude-repos":
 		// Handle repos command
-		a.handleListReposNoSession(channel, "")
+		a.handleListReposNoSession(ctx, channel, "")
+		return
+	case "/claude-usage":
+		a.handleUsage(ctx, channel, "", domain.ExtractUsageDays("usage "+text, defaultUsageDays))
+		return
+	case "/claude-admin":
+		a.handleAdminSlash(ctx, channel, user)
 		return
 	default:
-		a.slackClient.PostMessage(channel, fmt.Sprintf("未知のコマンド: %s", command))
+		a.slackClient.PostMessage(ctx, channel, fmt.Sprintf("未知のコマンド: %s", command))
 		return
 	}
 
 	if instruction == "" {
-		a.slackClient.PostMessage(channel, "指示が空です。コマンドの後に実装内容を指定してください。")
+		a.slackClient.PostMessage(ctx, channel, "指示が空です。コマンドの後に実装内容を指定してください。")
 		return
 	}
 
@@ -159,9 +295,9 @@ func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL str
 
 	if !exists {
 		// Post a message and use its timestamp as thread
-		msgTS, err := a.slackClient.PostMessageReturningTS(channel, fmt.Sprintf(":%s_hourglass_flowing_sand: タスクを開始します...", ""))
+		msgTS, err := a.slackClient.PostMessageReturningTS(ctx, channel, fmt.Sprintf(":%s_hourglass_flowing_sand: タスクを開始します...", ""))
 		if err != nil {
-			a.slackClient.PostMessage(channel, fmt.Sprintf(":x: メッセージ送信エラー: %s", err))
+			a.slackClient.PostMessage(ctx, channel, fmt.Sprintf(":x: メッセージ送信エラー: %s", err))
 			return
 		}
 		threadTS = msgTS
@@ -176,7 +312,7 @@ func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL str
 		a.logger.Info("new session from slash command", "thread", threadTS, "channel", channel, "user", user, "command", command, "repository", a.defaultRepo.Key())
 
 		// Add reaction
-		a.slackClient.AddReaction(channel, threadTS, "eyes")
+		a.slackClient.AddReaction(ctx, channel, threadTS, "eyes")
 
 		// Update status message
 		repo := session.GetRepository()
@@ -189,7 +325,7 @@ func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL str
 		if mode == domain.ModeReview {
 			modeIcon = ":mag:"
 		}
-		msgTS2, _ := a.slackClient.PostThreadMessageReturningTS(channel, threadTS,
+		msgTS2, _ := a.slackClient.PostThreadMessageReturningTS(ctx, channel, threadTS,
 			fmt.Sprintf("%s タスクを開始します... (リポジトリ: %s, モード: %s %s, %s %s)",
 				":hourglass_flowing_sand:", repo.Key(), modeIcon, mode.String(), execIcon, execMode.String()))
 		session.Mu.Lock()
@@ -197,16 +333,16 @@ func (a *Agent) HandleSlashCommand(command, text, channel, user, responseURL str
 		session.Mu.Unlock()
 
 		// Run in goroutine
-		go a.runClaude(session, instruction)
+		a.startRun(ctx, session, taskRequest{User: user, Prompt: instruction})
 	} else {
 		// Continue existing session
 		threadTS = session.ThreadTS
 		session.SetMode(mode)
-		a.continueSession(session, instruction)
+		a.continueSession(ctx, session, taskRequest{User: user, Prompt: instruction})
 	}
 }
 
-func (a *Agent) HandleMention(event slackclient.Event) {
+func (a *Agent) HandleMention(ctx context.Context, event slackclient.Event) {
 	channel := event.Channel
 	user := event.User
 	text := event.Text
@@ -225,6 +361,8 @@ func (a *Agent) HandleMention(event slackclient.Event) {
 
 	// Detect commands
 	cmd := domain.DetectCommand(instruction)
+	ctx, span := startDispatchSpan(ctx, cmd, threadTS)
+	defer span.End()
 
 	a.mu.RLock()
 	session, exists := a.sessions[threadTS]
@@ -240,38 +378,53 @@ func (a *Agent) HandleMention(event slackclient.Event) {
 
 		switch cmd {
 		case domain.CommandStop:
-			a.stopExecution(session)
+			a.stopExecution(ctx, session)
 			return
 		case domain.CommandEnd:
-			a.endSession(session, user)
+			a.endSession(ctx, session, user)
 			return
 		case domain.CommandReview:
 			session.SetMode(domain.ModeReview)
-			a.slackClient.PostThreadMessage(channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 				fmt.Sprintf(":mag: レビューモードに切り替えました"))
 			return
 		case domain.CommandImplement:
 			session.SetMode(domain.ModeImplementation)
-			a.slackClient.PostThreadMessage(channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 				fmt.Sprintf(":hammer_and_wrench: 実装モードに切り替えました"))
 			return
 		case domain.CommandSwitch:
-			a.handleSwitchRepo(session, instruction)
+			a.handleSwitchRepo(ctx, session, instruction)
 			return
 		case domain.CommandRepos:
-			a.handleListRepos(session)
+			a.handleListRepos(ctx, session)
 			return
 		case domain.CommandPRs:
-			a.handleListPRs(session)
+			a.handleListPRs(ctx, session)
+			return
+		case domain.CommandPRStatus:
+			a.handlePRStatus(ctx, channel, threadTS, domain.ExtractPRNumber(instruction))
+			return
+		case domain.CommandUsage:
+			a.handleUsage(ctx, channel, threadTS, domain.ExtractUsageDays(instruction, defaultUsageDays))
+			return
+		case domain.CommandResume:
+			a.handleResume(ctx, session, user)
+			return
+		case domain.CommandStatus:
+			a.handleStatus(ctx, channel, threadTS, user)
+			return
+		case domain.CommandTranscript:
+			a.handleTranscript(ctx, channel, threadTS)
 			return
 		case domain.CommandSync:
 			session.SetExecutionMode(domain.ExecutionSync)
-			a.slackClient.PostThreadMessage(channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 				fmt.Sprintf(":arrow_forward: 順次実行モードに切り替えました（タスクを1つずつ順番に実行）"))
 			return
 		case domain.CommandAsync:
 			session.SetExecutionMode(domain.ExecutionAsync)
-			a.slackClient.PostThreadMessage(channel, threadTS,
+			a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 				fmt.Sprintf(":fast_forward: 並列実行モードに切り替えました（複数タスクを同時実行）"))
 			return
 		}
@@ -280,10 +433,10 @@ func (a *Agent) HandleMention(event slackclient.Event) {
 		if session.Running() {
 			execMode := session.GetExecutionMode()
 			if execMode == domain.ExecutionSync {
-				a.slackClient.PostThreadMessage(channel, threadTS,
+				a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 					":hourglass: 順次実行モード：現在実行中です。完了後にもう一度メンションしてください。")
 			} else {
-				a.slackClient.PostThreadMessage(channel, threadTS,
+				a.slackClient.PostThreadMessage(ctx, channel, threadTS,
 					":warning: 並列実行モード：既に実行中です。新しいタスクを開始する場合は別のスレッドを使用してください。")
 			}
 			return
@@ -292,27 +445,53 @@ func (a *Agent) HandleMention(event slackclient.Event) {
 		// Handle non-session commands
 		switch cmd {
 		case domain.CommandRepos:
-			a.handleListReposNoSession(channel, threadTS)
+			a.handleListReposNoSession(ctx, channel, threadTS)
 			return
 		case domain.CommandPRs:
-			a.handleListPRsNoSession(channel, threadTS)
+			a.handleListPRsNoSession(ctx, channel, threadTS)
+			return
+		case domain.CommandPRStatus:
+			a.handlePRStatus(ctx, channel, threadTS, domain.ExtractPRNumber(instruction))
+			return
+		case domain.CommandUsage:
+			a.handleUsage(ctx, channel, threadTS, domain.ExtractUsageDays(instruction, defaultUsageDays))
+			return
+		case domain.CommandStatus:
+			a.handleStatus(ctx, channel, threadTS, user)
 			return
 		}
 	}
 
 	// Create new session if not exists
 	if !exists {
-		a.startNewSession(channel, threadTS, user, instruction)
+		a.startNewSession(ctx, channel, threadTS, taskRequest{User: user, Prompt: instruction, Files: event.Files})
 		return
 	}
 
 	// Continue existing session
-	a.continueSession(session, instruction)
+	a.continueSession(ctx, session, taskRequest{User: user, Prompt: instruction, Files: event.Files})
 }
 
-func (a *Agent) startNewSession(channel, threadTS, user, instruction string) {
-	if instruction == "" {
-		a.slackClient.PostThreadMessage(channel, threadTS, "指示が空です。ボットをメンションして実装内容を指示してください。")
+// taskRequest is a single instruction from a Slack user to run Claude.
+type taskRequest struct {
+	User   string // Slack user ID of the requester
+	Prompt string
+	Files  []slackclient.File
+
+	// ResumeSessionID resumes an existing Claude session instead of starting a new one.
+	ResumeSessionID string
+	// Options replace the inline options of Prompt, e.g. those of the
+	// interrupted task when resuming it with resumePrompt.
+	Options *domain.InlineOptions
+}
+
+func (r taskRequest) empty() bool {
+	return r.Prompt == "" && len(r.Files) == 0
+}
+
+func (a *Agent) startNewSession(ctx context.Context, channel, threadTS string, req taskRequest) {
+	if req.empty() {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, "指示が空です。ボットをメンションして実装内容を指示してください。")
 		return
 	}
 
@@ -323,10 +502,10 @@ func (a *Agent) startNewSession(channel, threadTS, user, instruction string) {
 	a.mu.Unlock()
 
 	repo := session.GetRepository()
-	a.logger.Info("new session", "thread", threadTS, "channel", channel, "user", user, "repository", repo.Key())
+	a.logger.Info("new session", "thread", threadTS, "channel", channel, "user", req.User, "repository", repo.Key())
 
 	// Add reaction
-	a.slackClient.AddReaction(channel, threadTS, "eyes")
+	a.slackClient.AddReaction(ctx, channel, threadTS, "eyes")
 
 	// Post initial message
 	execMode := session.GetExecutionMode()
@@ -334,7 +513,7 @@ func (a *Agent) startNewSession(channel, threadTS, user, instruction string) {
 	if execMode == domain.ExecutionSync {
 		execIcon = ":arrow_forward:"
 	}
-	msgTS, _ := a.slackClient.PostThreadMessageReturningTS(channel, threadTS,
+	msgTS, _ := a.slackClient.PostThreadMessageReturningTS(ctx, channel, threadTS,
 		fmt.Sprintf(":hourglass_flowing_sand: タスクを開始します... (リポジトリ: %s, モード: 実装, %s %s)",
 			repo.Key(), execIcon, execMode.String()))
 	session.Mu.Lock()
@@ -342,11 +521,11 @@ func (a *Agent) startNewSession(channel, threadTS, user, instruction string) {
 	session.Mu.Unlock()
 
 	// Run in goroutine
-	go a.runClaude(session, instruction)
+	a.startRun(ctx, session, req)
 }
 
-func (a *Agent) continueSession(session *domain.Session, instruction string) {
-	if instruction == "" {
+func (a *Agent) continueSession(ctx context.Context, session *domain.Session, req taskRequest) {
+	if req.empty() {
 		return
 	}
 
@@ -366,37 +545,108 @@ func (a *Agent) continueSession(session *domain.Session, instruction string) {
 	}
 
 	repo := session.GetRepository()
-	msgTS, _ := a.slackClient.PostThreadMessageReturningTS(session.Channel, session.ThreadTS,
+	msgTS, _ := a.slackClient.PostThreadMessageReturningTS(ctx, session.Channel, session.ThreadTS,
 		fmt.Sprintf(":speech_balloon: 会話を継続中... (リポジトリ: %s, モード: %s %s, %s %s)",
 			repo.Key(), modeIcon, mode.String(), execIcon, execMode.String()))
 	session.Mu.Lock()
 	session.StatusMsgTS = msgTS
 	session.Mu.Unlock()
 
-	go a.runClaude(session, instruction)
+	a.startRun(ctx, session, req)
 }
 
-func (a *Agent) runClaude(session *domain.Session, prompt string) {
+func (a *Agent) runClaude(ctx context.Context, session *domain.Session, req taskRequest) {
+	ctx, span := tracing.Tracer().Start(ctx, "claude.run", trace.WithAttributes(
+		attribute.String("slack.channel", session.Channel),
+		attribute.String("slack.thread_ts", session.ThreadTS),
+		attribute.String("slack.user", req.User),
+	))
+	defer span.End()
+	traceID := tracing.TraceID(ctx)
+
+	if !a.beginTask() {
+		a.updateMessage(ctx, session, ":construction: サーバーの再起動中のため、新しいタスクを受け付けていません。しばらくしてから再度お試しください。")
+		return
+	}
+	defer a.endTask()
+
 	session.SetRunning(true)
 	defer session.SetRunning(false)
 
 	startTime := time.Now()
 
+	// Parse inline options (e.g. timeout=60m) out of the instruction
+	opts, instruction, err := domain.ParseInlineOptions(req.Prompt)
+	if err != nil {
+		a.updateMessage(ctx, session, fmt.Sprintf(":warning: オプションの指定が不正です: %s", err))
+		return
+	}
+	if req.Options != nil {
+		opts = *req.Options
+	}
+
+	// repos=a,b makes this and later tasks of the thread span several
+	// repositories; the session keeps them once the task passes every check
+	repo, others := session.GetRepository(), session.GetRelated()
+	if len(opts.Repos) > 0 {
+		repos, err := a.resolveRepos(opts.Repos)
+		if err != nil {
+			a.updateMessage(ctx, session, fmt.Sprintf(":warning: オプションの指定が不正です: %s", err))
+			return
+		}
+		repo, others = repos[0], repos[1:]
+	}
+
 	// Get repository-specific runner
-	repo := session.GetRepository()
 	if repo == nil {
-		a.updateMessage(session, ":x: エラー: リポジトリが設定されていません")
+		a.updateMessage(ctx, session, ":x: エラー: リポジトリが設定されていません")
 		return
 	}
 
-	runner, exists := a.runners[repo.Key()]
+	b, exists := a.backends[repo.Key()]
 	if !exists {
-		a.updateMessage(session, fmt.Sprintf(":x: エラー: リポジトリ %s のRunnerが見つかりません", repo.Key()))
+		a.updateMessage(ctx, session, fmt.Sprintf(":x: エラー: リポジトリ %s のバックエンドが見つかりません", repo.Key()))
+		return
+	}
+
+	related, err := a.describeRelated(others)
+	if err != nil {
+		a.updateMessage(ctx, session, fmt.Sprintf(":x: エラー: %s", err))
 		return
 	}
+	requester := a.requesterIdentity(ctx, req.User)
 
-	// Create cancellable context
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	// Refuse to start if the user, daily or a repository's budget is exhausted
+	taskRepos := append([]string{repo.Key()}, repoKeys(others)...)
+	guard, err := a.budgets.NewTaskGuard(req.User, taskRepos...)
+	if err != nil {
+		a.logger.Info("budget exhausted", "thread", session.ThreadTS, "user", req.User, "repository", repo.Key(), "error", err)
+		a.updateMessage(ctx, session, budgetExhaustedMessage(err))
+		return
+	}
+	defer guard.Release()
+
+	// Get session info
+	mode := session.GetMode()
+
+	timeout, clamped := a.timeouts.Resolve(repo.Key(), mode, opts.Timeout)
+	if clamped {
+		a.updateMessage(ctx, session, fmt.Sprintf(":information_source: 指定できるタイムアウトの範囲外のため %s に調整しました", formatDuration(timeout)))
+	}
+
+	model, ok := a.models.Resolve(repo.Key(), mode, opts.Model)
+	if !ok {
+		a.updateMessage(ctx, session, modelNotAllowedMessage(opts.Model, a.models.Allowed))
+		return
+	}
+	if len(opts.Repos) > 0 {
+		session.SetRepositories(repo, others)
+	}
+
+	// Create cancellable context (the timeout covers the run only, not the scheduler wait).
+	// It derives from baseCtx so shutdown can interrupt the run; Slack calls keep
+	// using ctx so final messages are still sent after the run is cancelled.
+	runCtx, cancel := context.WithCancel(trace.ContextWithSpan(a.baseCtx, span))
 	defer cancel()
 
 	session.Mu.Lock()
@@ -405,59 +655,302 @@ func (a *Agent) runClaude(session *domain.Session, prompt string) {
 
 	logger := a.logger.With("thread", session.ThreadTS, "channel", session.Channel, "repository", repo.Key())
 
+	// Generate unique task ID to prevent context mixing in parallel execution
+	// Each task gets its own independent Claude session
+	taskID := session.GenerateTaskID()
+	logger.Info("generated task ID", "task_id", taskID)
+
+	task := &activeTask{
+		ID:         taskID,
+		Session:    session,
+		Request:    req,
+		Repository: repo.Key(),
+		Related:    repoKeys(others),
+		Mode:       mode,
+		StartedAt:  startTime,
+		Cancel:     cancel,
+		queued:     true,
+		preparing:  true,
+	}
+	a.registerTask(task)
+	defer a.unregisterTask(taskID)
+
+	span.SetAttributes(
+		attribute.String("task.id", taskID),
+		attribute.String("repository", repo.Key()),
+		attribute.String("mode", mode.String()),
+	)
+	metrics.RunsStarted.Inc(repo.Key(), mode.String())
+	metrics.RunsInProgress.Add(1)
+	defer metrics.RunsInProgress.Add(-1)
+
 	// Track progress
 	var textBuf strings.Builder
 	var toolHistory []toolEntry
 	lastUpdate := time.Now()
 	updateInterval := 3 * time.Second
-
-	callback := func(evt claude.ProgressEvent) {
+	budgetExceeded := false
+	toolSpans := newToolSpans(ctx)
+	defer toolSpans.endAll()
+
+	modelUsed := model // updated with what the backend reports, e.g. after a fallback
+	handleEvent := func(evt backend.Event) {
+		if evt.Model != "" {
+			modelUsed = evt.Model
+		}
 		switch evt.Type {
-		case claude.ProgressText:
+		case backend.EventSession:
+			task.setClaudeSessionID(evt.SessionID)
+
+		case backend.EventTurn:
+			switch guard.AddTurn(modelUsed, toBudgetUsage(evt.Usage)) {
+			case budget.GuardWarn:
+				a.updateMessage(ctx, session, budgetWarningMessage(guard))
+			case budget.GuardExceeded:
+				if !budgetExceeded {
+					budgetExceeded = true
+					logger.Info("task budget exceeded, cancelling", "turns", guard.Turns, "estimated_cost", guard.Cost)
+					cancel()
+				}
+			}
+
+		case backend.EventText:
 			textBuf.WriteString(evt.Text)
 			if time.Since(lastUpdate) > updateInterval {
-				a.sendProgressUpdate(session, textBuf.String(), toolHistory)
+				a.sendProgressUpdate(ctx, session, textBuf.String(), toolHistory)
 				lastUpdate = time.Now()
 			}
 
-		case claude.ProgressToolUse:
+		case backend.EventToolUse:
 			entry := toolEntry{
 				Name:    evt.ToolName,
-				Summary: claude.FormatToolSummary(evt.ToolName, evt.ToolInput),
+				Summary: evt.ToolSummary,
+			}
+			if entry.Summary == "" {
+				entry.Summary = evt.ToolName
 			}
 			toolHistory = append(toolHistory, entry)
-			a.sendProgressUpdate(session, textBuf.String(), toolHistory)
+			task.setCurrentTool(entry.Summary)
+			toolSpans.start(evt.ToolID, evt.ToolName, entry.Summary)
+			a.sendProgressUpdate(ctx, session, textBuf.String(), toolHistory)
 			lastUpdate = time.Now()
 
-		case claude.ProgressComplete:
-			if evt.Result != nil && evt.Result.IsError {
-				a.updateMessage(session, fmt.Sprintf(":warning: エラーが発生しました: %s", evt.Result.Result))
-			}
+		case backend.EventToolResult:
+			toolSpans.end(evt.ToolID, evt.IsError)
 		}
 	}
 
-	// Get session info
-	mode := session.GetMode()
+	// Clone the checkouts if they are missing (e.g. deleted since startup)
+	for _, r := range append([]*domain.Repository{repo}, others...) {
+		if err := a.ensureCheckout(ctx, r); err != nil {
+			logger.Error("repository checkout is not available", "error", err, "task_id", taskID, "checkout", r.Key())
+			a.updateMessage(ctx, session, fmt.Sprintf(":x: リポジトリ %s のワークスペースを用意できませんでした: %s", r.Key(), err))
+			return
+		}
+	}
+	// Commits made by the run must be signed: fail now rather than after the work is done
+	if mode == domain.ModeImplementation {
+		if err := a.checkSigning(ctx, repo); err != nil {
+			logger.Error("commit signing check failed", "error", err, "task_id", taskID)
+			a.updateMessage(ctx, session, signingErrorMessage(repo, err))
+			return
+		}
+	}
+	if len(related) > 0 {
+		a.updateMessage(ctx, session, fmt.Sprintf(":link: 複数リポジトリのタスク: %s（起点）, %s", repo.Key(), strings.Join(repoKeys(others), ", ")))
+	}
 
-	// Generate unique task ID to prevent context mixing in parallel execution
-	// Each task gets its own independent Claude session
-	taskID := session.GenerateTaskID()
-	logger.Info("generated task ID", "task_id", taskID)
+	// Download attachments into a per-task input directory inside the workspace
+	attachments, inputDir, err := a.downloadAttachments(ctx, b.WorkDir(), taskID, req.Files)
+	if err != nil {
+		logger.Error("failed to download attachments", "error", err, "task_id", taskID)
+		a.updateMessage(ctx, session, fmt.Sprintf(":x: 添付ファイルのダウンロードに失敗しました: %s", err))
+		return
+	}
+	if inputDir != "" {
+		defer os.RemoveAll(inputDir)
+		logger.Info("downloaded attachments", "task_id", taskID, "count", len(attachments))
+	}
+	prompt := buildAttachmentPrompt(instruction, attachments)
+
+	// Wait for a slot in the shared scheduler (not counted against the timeout)
+	slot := &runSlot{acquire: func(waitCtx context.Context) (func(), error) {
+		return a.waitForSlot(ctx, waitCtx, session, req.User, repo.Key(), repoKeys(others), mode)
+	}}
+	if err := slot.Acquire(runCtx); err != nil {
+		if a.baseCtx.Err() != nil {
+			logger.Info("queued task interrupted by shutdown", "task_id", taskID)
+			a.markInterrupted(task)
+			a.updateMessage(ctx, session, ":pause_button: サーバー再起動のため実行待ちのタスクを取り消しました。再起動後にこのスレッドで `resume` と送信すると実行できます。")
+		}
+		// Otherwise the wait was cancelled with `stop`, which already replied
+		return
+	}
+	defer slot.Release()
+	task.setQueued(false)
 
-	// Run claude without resuming from previous sessions
+	// Start from an up-to-date default branch unless resuming earlier work
+	baseSHA, err := a.prepareWorkspaces(ctx, runCtx, session, task, append([]*domain.Repository{repo}, others...), req.ResumeSessionID != "")
+	if err != nil {
+		switch {
+		case a.baseCtx.Err() != nil:
+			a.markInterrupted(task)
+			a.updateMessage(ctx, session, ":pause_button: サーバー再起動のためタスクを開始前に取り消しました。再起動後にこのスレッドで `resume` と送信すると実行できます。")
+		case runCtx.Err() == nil:
+			logger.Error("failed to prepare workspace", "error", err, "task_id", taskID)
+			a.updateMessage(ctx, session, workspaceErrorMessage(err))
+		}
+		// Otherwise cancelled with `stop`, which already replied
+		return
+	}
+	startTime = time.Now()
+
+	// Remember the branch each repository starts on, to tell a pull request
+	// the run opens from one that was already there
+	startBranches := make(map[string]string)
+	if mode == domain.ModeImplementation {
+		for _, r := range append([]*domain.Repository{repo}, others...) {
+			branch, err := workspace.CurrentBranch(ctx, a.backends[r.Key()].WorkDir())
+			if err != nil {
+				logger.Warn("failed to read current branch", "error", err, "task_id", taskID, "checkout", r.Key())
+			}
+			startBranches[r.Key()] = branch
+		}
+	}
+
+	// Warn shortly before the deadline
+	stopWarning := a.startTimeoutWarning(ctx, session, timeout)
+	defer stopWarning()
+
+	// Run without resuming from previous sessions
 	// This ensures each task is independent and prevents context mixing
-	result, err := runner.Run(ctx, prompt, mode, "", callback)
+	logger.Info("starting run", "task_id", taskID, "backend", b.Name(), "timeout", timeout.String())
+	span.SetAttributes(attribute.String("backend", b.Name()))
+	rec := a.startRecording(taskID, b.WorkDir())
+	timeoutCtx, cancelTimeout := context.WithTimeout(runCtx, timeout)
+	defer cancelTimeout()
+	notify := func(msg string) { a.updateMessage(ctx, session, msg) }
+	result, retries, err := a.executeWithRetry(timeoutCtx, b, backend.Task{
+		Prompt:          prompt,
+		Mode:            mode,
+		ResumeSessionID: req.ResumeSessionID,
+		MaxTurns:        guard.MaxTurns(),
+		Model:           model,
+		FallbackModel:   a.models.Fallback,
+		Related:         related,
+		Requester:       requester,
+		Transcript:      rec.transcript(),
+		Stderr:          rec.stderr(),
+	}, repo.Key(), slot, handleEvent, task.ClaudeSessionID, notify)
 	elapsed := time.Since(startTime)
+	timedOut := errors.Is(err, context.DeadlineExceeded)
+	interrupted := !budgetExceeded && errors.Is(err, context.Canceled) && a.baseCtx.Err() != nil
 
+	// Record spending (reported cost if available, otherwise our estimate)
+	cost := guard.Cost
+	if result != nil && result.Cost > 0 {
+		cost = result.Cost
+	}
+	guard.Release()
+	a.budgets.Record(req.User, taskRepos, cost)
+
+	outcome := ledger.OutcomeSuccess
+	switch {
+	case budgetExceeded:
+		outcome = ledger.OutcomeBudgetExceeded
+	case timedOut:
+		outcome = ledger.OutcomeTimedOut
+	case interrupted:
+		outcome = ledger.OutcomeInterrupted
+	case errors.Is(err, context.Canceled):
+		outcome = ledger.OutcomeCancelled
+	case err != nil || (result != nil && result.IsError):
+		outcome = ledger.OutcomeError
+	}
+	turns := guard.Turns
+	if result != nil && result.Turns > 0 {
+		turns = result.Turns
+	}
+	a.observeRun(repo.Key(), outcome, elapsed, cost)
+	span.SetAttributes(
+		attribute.String("outcome", string(outcome)),
+		attribute.Int("turns", turns),
+		attribute.Float64("cost_usd", cost),
+	)
 	if err != nil {
-		logger.Error("claude run failed", "error", err, "task_id", taskID)
-		a.updateMessage(session, fmt.Sprintf(":x: Claude実行エラー: %s", err))
+		span.RecordError(err)
+	}
+	if outcome != ledger.OutcomeSuccess {
+		span.SetStatus(codes.Error, string(outcome))
+	}
+	entry := ledger.Entry{
+		TaskID:     taskID,
+		StartedAt:  startTime,
+		User:       req.User,
+		Channel:    session.Channel,
+		ThreadTS:   session.ThreadTS,
+		Repository: repo.Key(),
+		Related:    repoKeys(others),
+		Mode:       mode.String(),
+		Model:      modelUsed,
+		BaseSHA:    baseSHA,
+		PromptHash: ledger.HashPrompt(req.Prompt),
+		DurationMS: elapsed.Milliseconds(),
+		Turns:      turns,
+		Cost:       cost,
+		Outcome:    outcome,
+	}
+	if mode == domain.ModeImplementation {
+		entry.PRURL = a.detectPullRequest(ctx, repo, b.WorkDir(), startBranches[repo.Key()], startTime, textBuf.String())
+		for _, r := range others {
+			// Claude's output can't tell which repository a URL belongs to, so
+			// related repositories rely on the git host alone
+			if url := a.detectPullRequest(ctx, r, a.backends[r.Key()].WorkDir(), startBranches[r.Key()], startTime, ""); url != "" {
+				entry.RelatedPRURLs = append(entry.RelatedPRURLs, url)
+			}
+		}
+	}
+	a.recordUsage(entry)
+	a.finishRecording(rec, entry)
+	info := summaryInfo{Model: modelUsed, BaseSHA: baseSHA, Related: repoKeys(others), PRURLs: append([]string{entry.PRURL}, entry.RelatedPRURLs...), TraceID: traceID, RunURL: a.runURL(taskID)}
+	if modelUsed != model && modelUsed == a.models.Fallback {
+		info.Model += "（フォールバック）"
+	}
+
+	if budgetExceeded {
+		summary := buildSummary(toolHistory, result, elapsed, info)
+		a.updateMessage(ctx, session, budgetExceededMessage(guard)+"\n\n"+summary)
+		return
+	}
+
+	if interrupted {
+		logger.Info("claude run interrupted by shutdown", "task_id", taskID)
+		a.markInterrupted(task)
+		a.updateMessage(ctx, session, ":pause_button: サーバー再起動のためタスクを中断しました。再起動後にこのスレッドで `resume` と送信すると再開できます。\n\n"+
+			buildSummary(toolHistory, result, elapsed, info))
+		return
+	}
+
+	if timedOut {
+		logger.Info("claude run timed out", "task_id", taskID, "timeout", timeout.String())
+		a.updateMessage(ctx, session, timedOutMessage(timeout, textBuf.String(), buildSummary(toolHistory, result, elapsed, info)))
+		a.slackClient.AddReaction(ctx, session.Channel, session.ThreadTS, "alarm_clock")
+		return
+	}
+
+	if err != nil {
+		logger.Error("claude run failed", "error", err, "kind", backend.KindOf(err), "retries", retries, "task_id", taskID)
+		msg := failureMessage(err, retries)
+		if summary := buildSummary(toolHistory, result, elapsed, info); summary != "" {
+			msg += "\n\n" + summary
+		}
+		a.updateMessage(ctx, session, msg)
 		return
 	}
 
 	// Build final message
 	finalText := textBuf.String()
-	summary := buildSummary(toolHistory, result, elapsed)
+	summary := buildSummary(toolHistory, result, elapsed, info)
 
 	var finalMsg string
 	if finalText != "" {
@@ -466,14 +959,34 @@ func (a *Agent) runClaude(session *domain.Session, prompt string) {
 		finalMsg = summary
 	}
 
-	a.updateMessage(session, finalMsg)
+	a.updateMessage(ctx, session, finalMsg)
 
 	// Add completion reaction
-	a.slackClient.AddReaction(session.Channel, session.ThreadTS, "white_check_mark")
+	a.slackClient.AddReaction(ctx, session.Channel, session.ThreadTS, "white_check_mark")
 	logger.Info("task completed successfully", "mode", mode.String())
 }
 
-func (a *Agent) stopExecution(session *domain.Session) {
+// modelNotAllowedMessage explains why a model=... override was rejected.
+func modelNotAllowedMessage(model string, allowed []string) string {
+	if len(allowed) == 0 {
+		return fmt.Sprintf(":warning: モデル `%s` は指定できません。モデルの指定は許可されていません。", model)
+	}
+	return fmt.Sprintf(":warning: モデル `%s` は指定できません。指定できるモデル: `%s`", model, strings.Join(allowed, "`, `"))
+}
+
+// execute runs a task on the backend and hands its events to handle until it ends.
+func (a *Agent) execute(ctx context.Context, b backend.Backend, task backend.Task, handle func(backend.Event)) (*backend.Result, error) {
+	execution, err := b.Start(ctx, task)
+	if err != nil {
+		return nil, fmt.Errorf("start %s: %w", b.Name(), err)
+	}
+	for evt := range execution.Events() {
+		handle(evt)
+	}
+	return execution.Wait()
+}
+
+func (a *Agent) stopExecution(ctx context.Context, session *domain.Session) {
 	session.Mu.Lock()
 	cancelFunc := session.CancelFunc
 	session.Mu.Unlock()
@@ -481,15 +994,15 @@ func (a *Agent) stopExecution(session *domain.Session) {
 	if cancelFunc != nil {
 		cancelFunc()
 		a.logger.Info("stopping execution", "thread", session.ThreadTS)
-		a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS,
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
 			":octagonal_sign: 実行を停止しました。")
 	} else {
-		a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS,
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
 			":information_source: 実行中のタスクがありません。")
 	}
 }
 
-func (a *Agent) endSession(session *domain.Session, user string) {
+func (a *Agent) endSession(ctx context.Context, session *domain.Session, user string) {
 	session.Deactivate()
 
 	a.logger.Info("ending session", "thread", session.ThreadTS, "user", user)
@@ -498,7 +1011,7 @@ func (a *Agent) endSession(session *domain.Session, user string) {
 	delete(a.sessions, session.ThreadTS)
 	a.mu.Unlock()
 
-	a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS,
+	a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
 		":wave: セッションを終了しました。")
 }
 
@@ -507,7 +1020,7 @@ type toolEntry struct {
 	Summary string
 }
 
-func (a *Agent) sendProgressUpdate(session *domain.Session, text string, tools []toolEntry) {
+func (a *Agent) sendProgressUpdate(ctx context.Context, session *domain.Session, text string, tools []toolEntry) {
 	// ツール実行時のみ新規メッセージを投稿（ログを残すため）
 	if len(tools) == 0 {
 		return
@@ -517,15 +1030,25 @@ func (a *Agent) sendProgressUpdate(session *domain.Session, text string, tools [
 	message := fmt.Sprintf(":wrench: %s", last.Summary)
 
 	// 新規メッセージとして投稿（更新しない）
-	a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS, message)
+	a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS, message)
 }
 
-func (a *Agent) updateMessage(session *domain.Session, text string) {
+func (a *Agent) updateMessage(ctx context.Context, session *domain.Session, text string) {
 	// 常に新規メッセージとして投稿（ログを残すため）
-	a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS, text)
+	a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS, text)
+}
+
+// summaryInfo is shown at the end of a summary.
+type summaryInfo struct {
+	Model   string // model the run used, if known
+	BaseSHA string   // commit the run started from, if the workspace was prepared
+	Related []string // other repositories of a multi-repository task
+	PRURLs  []string // pull requests the run opened, if any
+	TraceID string
+	RunURL  string // web UI page of the run
 }
 
-func buildSummary(tools []toolEntry, result *claude.Result, elapsed time.Duration) string {
+func buildSummary(tools []toolEntry, result *backend.Result, elapsed time.Duration, info summaryInfo) string {
 	if len(tools) == 0 && result == nil {
 		return ""
 	}
@@ -545,15 +1068,38 @@ func buildSummary(tools []toolEntry, result *claude.Result, elapsed time.Duratio
 	var stats []string
 	stats = append(stats, fmt.Sprintf(":stopwatch: %s", formatDuration(elapsed)))
 	if result != nil {
-		if result.NumTurns > 0 {
-			stats = append(stats, fmt.Sprintf("%d ターン", result.NumTurns))
+		if result.Turns > 0 {
+			stats = append(stats, fmt.Sprintf("%d ターン", result.Turns))
 		}
-		if result.TotalCost > 0 {
-			stats = append(stats, fmt.Sprintf("$%.4f", result.TotalCost))
+		if result.Cost > 0 {
+			stats = append(stats, fmt.Sprintf("$%.4f", result.Cost))
 		}
 	}
+	if info.Model != "" {
+		stats = append(stats, ":brain: "+info.Model)
+	}
+	if info.BaseSHA != "" {
+		stats = append(stats, fmt.Sprintf("base `%s`", shortSHA(info.BaseSHA)))
+	}
 	sb.WriteString(strings.Join(stats, "  |  "))
 
+	if len(info.Related) > 0 {
+		sb.WriteString(fmt.Sprintf("\n:card_index_dividers: 関連リポジトリ: %s", strings.Join(info.Related, ", ")))
+	}
+
+	for _, url := range info.PRURLs {
+		if url != "" {
+			sb.WriteString(fmt.Sprintf("\n:twisted_rightwards_arrows: PR: %s", url))
+		}
+	}
+
+	if info.RunURL != "" {
+		sb.WriteString(fmt.Sprintf("\n:page_facing_up: <%s|実行の詳細>", info.RunURL))
+	}
+	if info.TraceID != "" {
+		sb.WriteString(fmt.Sprintf("\n:link: trace: `%s`", info.TraceID))
+	}
+
 	return sb.String()
 }
 
@@ -590,17 +1136,17 @@ func formatForSlack(text string) string {
 	return strings.TrimSpace(strings.Join(result, "\n"))
 }
 
-func (a *Agent) handleSwitchRepo(session *domain.Session, text string) {
+func (a *Agent) handleSwitchRepo(ctx context.Context, session *domain.Session, text string) {
 	target := domain.ExtractSwitchTarget(text)
 	if target == "" {
-		a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS,
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
 			":warning: リポジトリ名を指定してください (例: `switch owner/repo`)")
 		return
 	}
 
-	// Find repository
-	repo := domain.FindRepository(a.repositories, target)
-	if repo == nil {
+	// Find repository (exact owner/name, then name, then partial match)
+	matches := domain.MatchRepositories(a.repositories, target)
+	if len(matches) == 0 {
 		// Repository not found, show available repositories
 		var repoList []string
 		for _, r := range a.repositories {
@@ -608,23 +1154,35 @@ func (a *Agent) handleSwitchRepo(session *domain.Session, text string) {
 		}
 		msg := fmt.Sprintf(":x: リポジトリ `%s` が見つかりません。利用可能なリポジトリ:\n%s",
 			target, strings.Join(repoList, "\n"))
-		a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS, msg)
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS, msg)
+		return
+	}
+	if len(matches) > 1 {
+		var repoList []string
+		for _, r := range matches {
+			repoList = append(repoList, fmt.Sprintf("• `switch %s`", r.Key()))
+		}
+		msg := fmt.Sprintf(":question: `%s` に一致するリポジトリが複数あります。どれに切り替えますか？\n%s",
+			target, strings.Join(repoList, "\n"))
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS, msg)
 		return
 	}
+	repo := matches[0]
 
 	// Switch repository
 	session.SetRepository(repo)
 	a.logger.Info("switched repository", "thread", session.ThreadTS, "repository", repo.Key())
-	a.slackClient.PostThreadMessage(session.Channel, session.ThreadTS,
+	a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
 		fmt.Sprintf(":arrows_counterclockwise: リポジトリを %s に切り替えました", repo.Key()))
 }
 
-func (a *Agent) handleListRepos(session *domain.Session) {
-	a.handleListReposNoSession(session.Channel, session.ThreadTS)
+func (a *Agent) handleListRepos(ctx context.Context, session *domain.Session) {
+	a.handleListReposNoSession(ctx, session.Channel, session.ThreadTS)
 }
 
-func (a *Agent) handleListReposNoSession(channel, threadTS string) {
+func (a *Agent) handleListReposNoSession(ctx context.Context, channel, threadTS string) {
 	currentRepo := ""
+	related := make(map[string]bool)
 	if threadTS != "" {
 		a.mu.RLock()
 		session, exists := a.sessions[threadTS]
@@ -634,6 +1192,9 @@ func (a *Agent) handleListReposNoSession(channel, threadTS string) {
 			if repo != nil {
 				currentRepo = repo.Key()
 			}
+			for _, r := range session.GetRelated() {
+				related[r.Key()] = true
+			}
 		}
 	}
 
@@ -642,66 +1203,58 @@ func (a *Agent) handleListReposNoSession(channel, threadTS string) {
 		marker := ""
 		if r.Key() == currentRepo {
 			marker = " :point_left: *現在のリポジトリ*"
+		} else if related[r.Key()] {
+			marker = " :link: *関連リポジトリ*"
 		} else if r.Key() == a.defaultRepo.Key() && currentRepo == "" {
 			marker = " _(デフォルト)_"
 		}
-		repoList = append(repoList, fmt.Sprintf("• %s (ブランチ: %s)%s", r.Key(), r.DefaultBranch, marker))
+		detail := "ブランチ: " + r.DefaultBranch
+		if r.Target.Name != "" {
+			detail += ", ディレクトリ: " + r.Target.Dir
+		}
+		repoList = append(repoList, fmt.Sprintf("• %s (%s)%s", r.Key(), detail, marker))
 	}
 
-	msg := fmt.Sprintf(":books: *利用可能なリポジトリ:*\n%s\n\nリポジトリを切り替えるには: `switch owner/repo`",
+	msg := fmt.Sprintf(":books: *利用可能なリポジトリ:*\n%s\n\nリポジトリを切り替えるには: `switch owner/repo`（名前だけでも可）\n複数のリポジトリにまたがるタスクは指示に `repos=backend,frontend` を付けてください",
 		strings.Join(repoList, "\n"))
-	a.slackClient.PostThreadMessage(channel, threadTS, msg)
+	a.slackClient.PostThreadMessage(ctx, channel, threadTS, msg)
 }
 
-func (a *Agent) handleListPRs(session *domain.Session) {
-	a.handleListPRsNoSession(session.Channel, session.ThreadTS)
+func (a *Agent) handleListPRs(ctx context.Context, session *domain.Session) {
+	a.handleListPRsNoSession(ctx, session.Channel, session.ThreadTS)
 }
 
-func (a *Agent) handleListPRsNoSession(channel, threadTS string) {
-	// Get current repository
-	var repo *domain.Repository
-	if threadTS != "" {
-		a.mu.RLock()
-		session, exists := a.sessions[threadTS]
-		a.mu.RUnlock()
-		if exists {
-			repo = session.GetRepository()
-		}
-	}
-	if repo == nil {
-		repo = a.defaultRepo
+func (a *Agent) handleListPRsNoSession(ctx context.Context, channel, threadTS string) {
+	repo := a.threadRepository(threadTS)
+	host := a.hostFor(repo)
+	if host == nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":warning: %s のGitホストが設定されていません。", repo.Key()))
+		return
 	}
 
-	a.logger.Info("listing PRs", "repository", repo.Key())
+	a.logger.Info("listing PRs", "repository", repo.Key(), "host", host.Name())
 
-	// Execute gh pr list
-	cmd := exec.Command("gh", "pr", "list", "--repo", fmt.Sprintf("https://github.com/%s/%s", repo.Owner, repo.Name), "--limit", "10")
-	output, err := cmd.CombinedOutput()
+	prs, err := host.ListPullRequests(ctx, repo, maxPRsShown)
+	noun := prNoun(host)
 	if err != nil {
-		a.slackClient.PostThreadMessage(channel, threadTS,
-			fmt.Sprintf(":x: PR一覧の取得に失敗しました: %s\n```\n%s\n```", err, string(output)))
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":x: %s一覧の取得に失敗しました: %s", noun, err))
 		return
 	}
 
-	outputStr := strings.TrimSpace(string(output))
-	if outputStr == "" {
-		a.slackClient.PostThreadMessage(channel, threadTS,
-			fmt.Sprintf(":mag: *%s のPR一覧:*\n\n現在、オープンなPRはありません。", repo.Key()))
+	if len(prs) == 0 {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":mag: *%s の%s一覧:*\n\n現在、オープンな%sはありません。", repo.Key(), noun, noun))
 		return
 	}
 
-	// Format output for Slack
-	lines := strings.Split(outputStr, "\n")
 	var prList []string
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		// GitHub CLI output format: "#123  PR Title  branch-name"
-		prList = append(prList, "• "+line)
+	for _, pr := range prs {
+		prList = append(prList, formatPullRequest(host, pr))
 	}
 
-	msg := fmt.Sprintf(":mag: *%s のPR一覧:*\n```\n%s\n```",
-		repo.Key(), strings.Join(prList, "\n"))
-	a.slackClient.PostThreadMessage(channel, threadTS, msg)
+	msg := fmt.Sprintf(":mag: *%s の%s一覧:*\n%s\n\nレビューとチェックの状況を見るには: `%s <番号>`",
+		repo.Key(), noun, strings.Join(prList, "\n"), strings.ToLower(noun))
+	a.slackClient.PostThreadMessage(ctx, channel, threadTS, msg)
 }
@@ -0,0 +1,159 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/agent"
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/budget"
+	"github.com/toshin/slack-claude-agent/internal/claude"
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+)
+
+const (
+	testChannel  = "C0000000001"
+	testUser     = "U0000000001"
+	testThreadTS = "1600000000.000001" // timestamp of the mention that starts the session
+)
+
+// newReplayAgent returns an agent whose runs replay testdata/readme-fix.jsonl
+// and whose Slack calls are recorded by the returned fake.
+func newReplayAgent(t *testing.T) (*agent.Agent, *slackclient.FakeClient) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	recording, err := runstore.LoadRecording("testdata/readme-fix.jsonl")
+	if err != nil {
+		t.Fatalf("load recording: %v", err)
+	}
+	repos, err := domain.ParseRepositories("example/repo", "main")
+	if err != nil {
+		t.Fatalf("parse repositories: %v", err)
+	}
+	backends := map[string]backend.Backend{
+		repos[0].Key(): claude.NewReplayRunner(claude.ReplayConfig{
+			Open:    recording.Open,
+			Offsets: recording.Offsets,
+			WorkDir: t.TempDir(),
+		}, logger),
+	}
+
+	fake := slackclient.NewFakeClient()
+	ag := agent.New(agent.Config{
+		SlackClient:  fake,
+		Backends:     backends,
+		Repositories: repos,
+		DefaultRepo:  repos[0],
+		Budgets:      budget.NewTracker(budget.Limits{}),
+		Timeouts:     domain.TimeoutPolicy{Default: 30 * time.Minute, Min: time.Minute, Max: time.Hour},
+		Scheduler:    scheduler.New(scheduler.Limits{}),
+		AdminUsers:   []string{testUser},
+	}, logger)
+	return ag, fake
+}
+
+// send delivers text as the mention starting the thread (first message) or as
+// a thread message, and waits until the agent has handled it.
+func send(t *testing.T, ag *agent.Agent, seq int, text string) {
+	t.Helper()
+	ts := fmt.Sprintf("1600000000.%06d", seq+1)
+	ctx := context.Background()
+	if seq == 0 {
+		ag.HandleMention(ctx, slackclient.Event{Type: "app_mention", User: testUser, Text: text, Channel: testChannel, TS: ts})
+	} else {
+		ag.HandleThreadMessage(ctx, slackclient.Event{Type: "message", User: testUser, Text: text, Channel: testChannel, TS: ts, ThreadTS: testThreadTS})
+	}
+	if !ag.Wait(time.Minute) {
+		t.Fatalf("timed out waiting for %q", text)
+	}
+}
+
+// texts returns the text of every posted or updated message.
+func texts(fake *slackclient.FakeClient) []string {
+	var out []string
+	for _, c := range fake.Calls() {
+		if c.Method == "chat.postMessage" || c.Method == "chat.update" {
+			out = append(out, c.Text)
+		}
+	}
+	return out
+}
+
+func containsMessage(messages []string, substr string) bool {
+	for _, m := range messages {
+		if strings.Contains(m, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReplayedRunPostsProgressAndSummary(t *testing.T) {
+	ag, fake := newReplayAgent(t)
+	send(t, ag, 0, "<@UBOT> READMEの誤字を直して")
+
+	messages := texts(fake)
+	for _, want := range []string{
+		":hourglass_flowing_sand: タスクを開始します",
+		":wrench: .../work/README.md を読み取り",
+		":wrench: .../work/README.md を編集",
+		":wrench: `git commit -am \"Fix typo in README\"`",
+	} {
+		if !containsMessage(messages, want) {
+			t.Errorf("no message containing %q in:\n%s", want, fake.Transcript())
+		}
+	}
+
+	final := messages[len(messages)-1]
+	for _, want := range []string{
+		"README の誤字 *recieve* を receive に修正してコミットしました。", // Markdown bold converted to Slack's
+		":clipboard: *実行ログ:*\n1. .../work/README.md を読み取り\n2. .../work/README.md を編集\n3. `git commit",
+		"4 ターン",
+		"$0.0123",
+		":brain: claude-sonnet-4-5",
+	} {
+		if !strings.Contains(final, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, final)
+		}
+	}
+
+	calls := fake.Calls()
+	if last := calls[len(calls)-1]; last.Method != "reactions.add" || last.Text != ":white_check_mark:" {
+		t.Errorf("last call = %s %q, want the completion reaction", last.Method, last.Text)
+	}
+}
+
+func TestThreadCommands(t *testing.T) {
+	ag, fake := newReplayAgent(t)
+	send(t, ag, 0, "<@UBOT> READMEの誤字を直して")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"usage", ":information_source: 利用状況の記録が無効になっています。"},
+		{"resume", ":information_source: このスレッドに中断されたタスクはありません。"},
+		// Instructions that merely start like a command or mention an option run as tasks
+		{"usage of the deprecated API を直して", "4 ターン"},
+		{"set the http client timeout=5s in config.go", "4 ターン"},
+		{"timeout=10s READMEを直して", ":information_source: 指定できるタイムアウトの範囲外のため 1分0秒 に調整しました"},
+		{"timeout=abc READMEを直して", ":warning: オプションの指定が不正です: invalid timeout \"abc\""},
+		{"model=claude-opus-4-1 READMEを直して", ":warning: モデル `claude-opus-4-1` は指定できません。"},
+	}
+	for i, tt := range tests {
+		before := len(texts(fake))
+		send(t, ag, i+1, tt.text)
+		if got := texts(fake)[before:]; !containsMessage(got, tt.want) {
+			t.Errorf("%q: no message containing %q in:\n%s", tt.text, tt.want, strings.Join(got, "\n---\n"))
+		}
+	}
+}
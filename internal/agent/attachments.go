@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	slackclient "github.com/toshin/slack-claude-agent/internal/slack"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
+)
+
+// InputDirName is the directory inside the repository checkout where
+// attachments are stored, one subdirectory per task.
+const InputDirName = ".slack-inputs"
+
+// maxAttachmentSize caps the size of a single downloaded attachment.
+const maxAttachmentSize = 50 * 1024 * 1024 // 50MB
+
+var unsafeFileNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type attachment struct {
+	Name     string
+	Path     string // absolute path on disk
+	Mimetype string
+	Size     int
+}
+
+// errAttachmentTooLarge is returned by the download writer past maxAttachmentSize.
+var errAttachmentTooLarge = errors.New("attachment too large")
+
+// downloadAttachments downloads Slack files into <workDir>/.slack-inputs/<taskID>.
+// The directory is added to the checkout's .git/info/exclude so the files
+// can't be committed. It returns the downloaded attachments and the directory
+// to clean up afterwards.
+func (a *Agent) downloadAttachments(ctx context.Context, workDir, taskID string, files []slackclient.File) ([]attachment, string, error) {
+	if len(files) == 0 {
+		return nil, "", nil
+	}
+
+	if err := workspace.Exclude(ctx, workDir, InputDirName+"/"); err != nil {
+		return nil, "", fmt.Errorf("exclude input dir from git: %w", err)
+	}
+	inputDir := filepath.Join(workDir, InputDirName, taskID)
+	if err := os.MkdirAll(inputDir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("create input dir: %w", err)
+	}
+
+	var attachments []attachment
+	for i, f := range files {
+		if f.Size > maxAttachmentSize {
+			os.RemoveAll(inputDir)
+			return nil, "", fmt.Errorf("%s is too large (%d bytes, max %d)", f.Name, f.Size, maxAttachmentSize)
+		}
+
+		name := sanitizeFileName(f.Name)
+		if name == "" {
+			name = fmt.Sprintf("file-%d", i+1)
+		}
+		// Prefix with index so files with the same name don't overwrite each other
+		path := filepath.Join(inputDir, fmt.Sprintf("%02d-%s", i+1, name))
+
+		if err := a.downloadFile(ctx, f.URL, path); err != nil {
+			os.RemoveAll(inputDir)
+			if errors.Is(err, errAttachmentTooLarge) {
+				return nil, "", fmt.Errorf("%s is too large (max %d bytes)", f.Name, maxAttachmentSize)
+			}
+			return nil, "", fmt.Errorf("download %s: %w", f.Name, err)
+		}
+
+		attachments = append(attachments, attachment{
+			Name:     f.Name,
+			Path:     path,
+			Mimetype: f.Mimetype,
+			Size:     f.Size,
+		})
+	}
+
+	return attachments, inputDir, nil
+}
+
+func (a *Agent) downloadFile(ctx context.Context, url, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	// Slack's declared size is checked up front; this stops downloads that turn out bigger
+	return a.slackClient.DownloadFile(ctx, url, &limitedWriter{w: out, remaining: maxAttachmentSize})
+}
+
+// limitedWriter fails with errAttachmentTooLarge once more than remaining
+// bytes are written.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, errAttachmentTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(name)
+	name = unsafeFileNameRe.ReplaceAllString(name, "_")
+	return strings.Trim(name, "._")
+}
+
+// buildAttachmentPrompt appends references to the downloaded attachments to the prompt.
+func buildAttachmentPrompt(prompt string, attachments []attachment) string {
+	if len(attachments) == 0 {
+		return prompt
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nThe user attached the following files (read them with the Read tool; images can be viewed directly):\n")
+	for _, att := range attachments {
+		sb.WriteString(fmt.Sprintf("- %s (%s, %d bytes): %s\n", att.Name, att.Mimetype, att.Size, att.Path))
+	}
+	sb.WriteString("These files are inputs only. Do NOT commit them or add them to a pull request.\n")
+	return sb.String()
+}
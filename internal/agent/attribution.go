@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// requesterIdentity returns the git identity of the Slack user a task runs
+// for, or nil if it is unknown or per-user attribution is disabled.
+func (a *Agent) requesterIdentity(ctx context.Context, user string) *domain.GitIdentity {
+	if a.identities == nil {
+		return nil
+	}
+	id, ok := a.identities.Lookup(ctx, user)
+	if !ok {
+		a.logger.Info("no git identity for user, attributing commits to the bot", "user", user)
+		return nil
+	}
+	return &id
+}
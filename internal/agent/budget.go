@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/budget"
+)
+
+func toBudgetUsage(u *backend.Usage) budget.Usage {
+	if u == nil {
+		return budget.Usage{}
+	}
+	return budget.Usage{
+		InputTokens:              u.InputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+	}
+}
+
+func budgetExhaustedMessage(err error) string {
+	var exhausted *budget.ExhaustedError
+	if !errors.As(err, &exhausted) {
+		return fmt.Sprintf(":money_with_wings: 予算を確認できませんでした: %s", err)
+	}
+
+	scope := "本日の全体"
+	switch exhausted.Scope {
+	case "user":
+		scope = "あなたの本日の"
+	case "repository":
+		scope = "このリポジトリの本日の"
+	}
+	return fmt.Sprintf(":money_with_wings: %s予算を使い切ったため実行できません（$%.2f / $%.2f）。明日以降に再度お試しいただくか、管理者に連絡してください。",
+		scope, exhausted.Spent, exhausted.Limit)
+}
+
+func budgetWarningMessage(g *budget.TaskGuard) string {
+	return fmt.Sprintf(":warning: このタスクの予算上限に近づいています（%s）。上限に達すると自動で停止します。", formatGuardUsage(g))
+}
+
+func budgetExceededMessage(g *budget.TaskGuard) string {
+	return fmt.Sprintf(":money_with_wings: タスクの予算上限に達したため実行を停止しました（%s）。", formatGuardUsage(g))
+}
+
+func formatGuardUsage(g *budget.TaskGuard) string {
+	s := fmt.Sprintf("%d ターン", g.Turns)
+	if g.MaxTurns() > 0 {
+		s = fmt.Sprintf("%d / %d ターン", g.Turns, g.MaxTurns())
+	}
+	if g.MaxCost() > 0 {
+		s += fmt.Sprintf(", 推定 $%.2f / $%.2f", g.Cost, g.MaxCost())
+	} else {
+		s += fmt.Sprintf(", 推定 $%.2f", g.Cost)
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// resolveRepos maps the targets of repos=a,b to configured repositories. Each
+// target must match exactly one repository and each checkout may appear once.
+func (a *Agent) resolveRepos(targets []string) ([]*domain.Repository, error) {
+	var repos []*domain.Repository
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		matches := domain.MatchRepositories(a.repositories, target)
+		switch {
+		case len(matches) == 0:
+			return nil, fmt.Errorf("repository %q not found", target)
+		case len(matches) > 1:
+			var keys []string
+			for _, m := range matches {
+				keys = append(keys, m.Key())
+			}
+			return nil, fmt.Errorf("%q matches several repositories (%s)", target, strings.Join(keys, ", "))
+		}
+		repo := matches[0]
+		if seen[repo.RepoKey()] {
+			return nil, fmt.Errorf("%s is given more than once", repo.RepoKey())
+		}
+		seen[repo.RepoKey()] = true
+		repos = append(repos, repo)
+	}
+	if err := a.checkSameEnvironment(repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// checkSameEnvironment makes sure the repositories of a multi-repository task
+// can share the environment of the primary one's backend: the same git host
+// server settings (GH_HOST, GITLAB_HOST) and the same commit signing.
+func (a *Agent) checkSameEnvironment(repos []*domain.Repository) error {
+	hostEnv := func(repo *domain.Repository) string {
+		if host := a.hostFor(repo); host != nil {
+			return strings.Join(host.Env(), " ")
+		}
+		return ""
+	}
+	primary := repos[0]
+	primarySigning, _ := domain.LookupRepo(a.signing, primary.Key())
+	for _, repo := range repos[1:] {
+		if hostEnv(repo) != hostEnv(primary) {
+			return fmt.Errorf("%s and %s are on different git host servers and can't be changed in one task", primary.Key(), repo.Key())
+		}
+		if sign, _ := domain.LookupRepo(a.signing, repo.Key()); sign != primarySigning {
+			return fmt.Errorf("%s and %s sign commits differently and can't be changed in one task", primary.Key(), repo.Key())
+		}
+	}
+	return nil
+}
+
+// describeRelated describes the related repositories of a session to the backend.
+func (a *Agent) describeRelated(repos []*domain.Repository) ([]backend.RelatedRepo, error) {
+	related := make([]backend.RelatedRepo, 0, len(repos))
+	for _, repo := range repos {
+		b, ok := a.backends[repo.Key()]
+		if !ok {
+			return nil, fmt.Errorf("no backend for repository %s", repo.Key())
+		}
+		rel := backend.RelatedRepo{
+			Key:           repo.RepoKey(),
+			Dir:           b.WorkDir(),
+			DefaultBranch: repo.DefaultBranch,
+		}
+		if host := a.hostFor(repo); host != nil {
+			rel.GitHost = host.Name()
+		}
+		related = append(related, rel)
+	}
+	return related, nil
+}
+
+func repoKeys(repos []*domain.Repository) []string {
+	keys := make([]string, len(repos))
+	for i, repo := range repos {
+		keys[i] = repo.Key()
+	}
+	return keys
+}
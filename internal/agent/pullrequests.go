@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
+)
+
+// prLookupTimeout bounds the git host calls made after a run to find the
+// pull request it opened.
+const prLookupTimeout = 30 * time.Second
+
+// prClockSkew is how much earlier than the run's start a pull request may be
+// timestamped by the git host and still count as opened by the run: host
+// clocks drift and GitHub truncates timestamps to the second. The branch
+// check rules out older pull requests.
+const prClockSkew = time.Minute
+
+// maxPRsShown caps the pull requests listed by the prs command.
+const maxPRsShown = 10
+
+// hostFor returns the git host of repo, or nil if none is configured.
+func (a *Agent) hostFor(repo *domain.Repository) githost.Host {
+	h, _ := domain.LookupRepo(a.hosts, repo.Key())
+	return h
+}
+
+// threadRepository returns the repository of the thread's session, or the
+// default repository if the thread has none.
+func (a *Agent) threadRepository(threadTS string) *domain.Repository {
+	if threadTS != "" {
+		a.mu.RLock()
+		session, exists := a.sessions[threadTS]
+		a.mu.RUnlock()
+		if exists {
+			return session.GetRepository()
+		}
+	}
+	return a.defaultRepo
+}
+
+// prNoun returns what the host calls pull requests, for messages.
+func prNoun(host githost.Host) string {
+	if host.Name() == githost.HostGitLab {
+		return "MR"
+	}
+	return "PR"
+}
+
+// prRef returns how the host refers to pull request n (GitLab: !n).
+func prRef(host githost.Host, n int) string {
+	if host.Name() == githost.HostGitLab {
+		return fmt.Sprintf("!%d", n)
+	}
+	return fmt.Sprintf("#%d", n)
+}
+
+func formatPullRequest(host githost.Host, pr githost.PullRequest) string {
+	line := fmt.Sprintf("• <%s|%s> %s (`%s`)", pr.URL, prRef(host, pr.Number), pr.Title, pr.Branch)
+	if pr.Author != "" {
+		line += " by " + pr.Author
+	}
+	if pr.Draft {
+		line += " _(ドラフト)_"
+	}
+	return line
+}
+
+// handlePRStatus shows the reviews and CI checks of a pull request (merge request).
+func (a *Agent) handlePRStatus(ctx context.Context, channel, threadTS string, number int) {
+	repo := a.threadRepository(threadTS)
+	host := a.hostFor(repo)
+	if host == nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":warning: %s のGitホストが設定されていません。", repo.Key()))
+		return
+	}
+
+	a.logger.Info("showing PR status", "repository", repo.Key(), "number", number, "host", host.Name())
+	reviews, err := host.Reviews(ctx, repo, number)
+	if err != nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":x: %s のレビューの取得に失敗しました: %s", prRef(host, number), err))
+		return
+	}
+	checks, err := host.Checks(ctx, repo, number)
+	if err != nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS,
+			fmt.Sprintf(":x: %s のチェックの取得に失敗しました: %s", prRef(host, number), err))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":mag: *%s %s の状況:*\n", repo.Key(), prRef(host, number)))
+
+	// Only the latest review of each reviewer counts
+	latest := make(map[string]githost.Review)
+	var reviewers []string
+	for _, r := range reviews {
+		if _, ok := latest[r.Author]; !ok {
+			reviewers = append(reviewers, r.Author)
+		}
+		latest[r.Author] = r
+	}
+	sb.WriteString("\n*レビュー:*\n")
+	if len(reviewers) == 0 {
+		sb.WriteString("まだレビューはありません。\n")
+	}
+	for _, name := range reviewers {
+		sb.WriteString(fmt.Sprintf("%s %s\n", reviewEmoji(latest[name].State), name))
+	}
+
+	sb.WriteString("\n*チェック:*\n")
+	if len(checks) == 0 {
+		sb.WriteString("チェックはありません。\n")
+	}
+	counts := make(map[githost.CheckState]int)
+	for _, c := range checks {
+		counts[c.State]++
+		name := c.Name
+		if c.URL != "" {
+			name = fmt.Sprintf("<%s|%s>", c.URL, c.Name)
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", checkEmoji(c.State), name))
+	}
+	if len(checks) > 0 {
+		sb.WriteString(fmt.Sprintf("成功 %d / 失敗 %d / 実行中 %d / スキップ %d",
+			counts[githost.CheckSuccess], counts[githost.CheckFailure], counts[githost.CheckPending], counts[githost.CheckSkipped]))
+	}
+
+	a.slackClient.PostThreadMessage(ctx, channel, threadTS, strings.TrimRight(sb.String(), "\n"))
+}
+
+func reviewEmoji(state githost.ReviewState) string {
+	switch state {
+	case githost.ReviewApproved:
+		return ":white_check_mark:"
+	case githost.ReviewChangesRequested:
+		return ":warning:"
+	default:
+		return ":speech_balloon:"
+	}
+}
+
+func checkEmoji(state githost.CheckState) string {
+	switch state {
+	case githost.CheckSuccess:
+		return ":white_check_mark:"
+	case githost.CheckFailure:
+		return ":x:"
+	case githost.CheckSkipped:
+		return ":heavy_minus_sign:"
+	default:
+		return ":hourglass_flowing_sand:"
+	}
+}
+
+// detectPullRequest returns the URL of the pull request an implementation run
+// opened: an open pull request created since the run started, for the branch
+// left checked out in workDir if the run switched away from startBranch. The
+// last pull request URL in Claude's output is used only when the git host is
+// unknown or can't be asked.
+func (a *Agent) detectPullRequest(ctx context.Context, repo *domain.Repository, workDir, startBranch string, since time.Time, output string) string {
+	host := a.hostFor(repo)
+	if host == nil {
+		return extractPRURL(output)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, prLookupTimeout)
+	defer cancel()
+	branch, err := workspace.CurrentBranch(ctx, workDir)
+	if err != nil {
+		a.logger.Warn("failed to read current branch", "repository", repo.Key(), "error", err)
+		return extractPRURL(output)
+	}
+	if branch == "HEAD" || branch == repo.DefaultBranch || branch == startBranch {
+		return ""
+	}
+	pr, err := host.FindPullRequest(ctx, repo, branch)
+	if err != nil {
+		a.logger.Warn("failed to look up pull request", "repository", repo.Key(), "branch", branch, "error", err)
+		return extractPRURL(output)
+	}
+	if pr == nil || pr.State != "open" || pr.CreatedAt.Before(since.Add(-prClockSkew)) {
+		return ""
+	}
+	return pr.URL
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
+)
+
+// waitForSlot blocks until the scheduler lets the task run. While it waits, the
+// queue position and ETA are posted to the thread and kept up to date.
+// runCtx cancels the wait (stop, shutdown); ctx is used for Slack calls.
+func (a *Agent) waitForSlot(ctx, runCtx context.Context, session *domain.Session, user, repo string, related []string, mode domain.AgentMode) (func(), error) {
+	_, span := tracing.Tracer().Start(runCtx, "scheduler.wait")
+	defer span.End()
+
+	req := scheduler.Request{
+		User:     user,
+		Repo:     repo,
+		Related:  related,
+		Priority: a.priorities[mode],
+	}
+
+	var queuedTS string
+	waitStart := time.Now()
+	release, err := a.scheduler.Acquire(runCtx, req, func(pos scheduler.Position) {
+		text := queuedMessage(pos)
+		if queuedTS == "" {
+			ts, err := a.slackClient.PostThreadMessageReturningTS(ctx, session.Channel, session.ThreadTS, text)
+			if err != nil {
+				a.logger.Error("failed to post queue position", "error", err, "thread", session.ThreadTS)
+				return
+			}
+			queuedTS = ts
+			return
+		}
+		a.slackClient.UpdateThreadMessage(ctx, session.Channel, queuedTS, text)
+	})
+	waited := time.Since(waitStart)
+	span.SetAttributes(attribute.Bool("queued", queuedTS != ""))
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.SemaphoreWait.Observe(waited.Seconds(), repo)
+	if queuedTS != "" {
+		a.slackClient.UpdateThreadMessage(ctx, session.Channel, queuedTS,
+			fmt.Sprintf(":arrow_forward: 順番が来たので実行を開始します（待ち時間: %s）", formatDuration(waited)))
+	}
+	return release, nil
+}
+
+func queuedMessage(pos scheduler.Position) string {
+	msg := fmt.Sprintf(":hourglass_flowing_sand: 実行待ちです（%d番目）", pos.Ahead+1)
+	if pos.ETA > 0 {
+		msg += fmt.Sprintf("。開始まで約%s", formatDuration(pos.ETA))
+	}
+	return msg + "。`stop` で取り消せます。"
+}
+
+// runSlot is a task's scheduler slot. It is handed back while the task backs
+// off before a retry, so other queued work can run in the meantime.
+type runSlot struct {
+	acquire func(ctx context.Context) (func(), error)
+	release func()
+}
+
+// Acquire waits for a slot until ctx is done.
+func (s *runSlot) Acquire(ctx context.Context) error {
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	s.release = release
+	return nil
+}
+
+// Release gives the slot back; it does nothing if the slot is not held.
+func (s *runSlot) Release() {
+	if s.release != nil {
+		s.release()
+		s.release = nil
+	}
+}
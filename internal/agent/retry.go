@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+)
+
+// retryPrompt is sent when resuming a session after a transient failure.
+const retryPrompt = "The previous attempt failed with a temporary error. Check the current state of the repository and continue the task from where you left off."
+
+// executeWithRetry runs a task and retries transient failures (rate limit,
+// overload, network) with backoff, resuming the backend session when one was
+// started. The scheduler slot is released during the backoff and acquired again
+// before the retry; both the backoff and that wait count against ctx's deadline
+// (the task timeout). notify posts progress to the thread. It returns the last
+// attempt's result, with cost and turns summed over all attempts, and the
+// number of retries made.
+func (a *Agent) executeWithRetry(ctx context.Context, b backend.Backend, task backend.Task, repo string, slot *runSlot,
+	handle func(backend.Event), sessionID func() string, notify func(string)) (*backend.Result, int, error) {
+	var earlier backend.Result // cost and turns of failed attempts
+	finish := func(result *backend.Result, retries int, err error) (*backend.Result, int, error) {
+		if result != nil {
+			result.Cost += earlier.Cost
+			result.Turns += earlier.Turns
+		}
+		return result, retries, err
+	}
+
+	for retry := 1; ; retry++ {
+		result, err := a.execute(ctx, b, task, handle)
+		kind := backend.KindOf(err)
+		if !kind.Retryable() || retry > a.retries.Max || ctx.Err() != nil {
+			return finish(result, retry-1, err)
+		}
+
+		delay := a.retries.Delay(retry)
+		a.logger.Warn("transient backend failure, retrying", "error", err, "kind", kind, "retry", retry, "delay", delay.String(), "repository", repo)
+		metrics.RunRetries.Inc(repo, string(kind))
+		notify(fmt.Sprintf(":arrows_counterclockwise: %sのため、%s後に再試行します（%d/%d）",
+			failureLabel(kind), formatDuration(delay), retry, a.retries.Max))
+
+		slot.Release()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return finish(result, retry-1, ctx.Err())
+		case <-timer.C:
+		}
+		if err := slot.Acquire(ctx); err != nil {
+			return finish(result, retry-1, err)
+		}
+		if result != nil {
+			earlier.Cost += result.Cost
+			earlier.Turns += result.Turns
+		}
+
+		// Continue where the failed attempt stopped rather than starting over
+		if id := sessionID(); id != "" {
+			task.ResumeSessionID = id
+			task.Prompt = retryPrompt
+		}
+	}
+}
+
+func failureLabel(kind backend.FailureKind) string {
+	switch kind {
+	case backend.FailureRateLimited:
+		return "レート制限"
+	case backend.FailureOverloaded:
+		return "APIの過負荷"
+	case backend.FailureNetwork:
+		return "ネットワークエラー"
+	}
+	return "一時的なエラー"
+}
+
+// failureMessage explains a failed run and what to do about it.
+func failureMessage(err error, retries int) string {
+	switch kind := backend.KindOf(err); kind {
+	case backend.FailureAuth:
+		return ":key: Claude の認証が切れています。管理者はサーバーで `claude` を実行してログインし直してください。ログイン後、このスレッドで再度指示すると実行できます。"
+	case backend.FailureMaxTurns:
+		return ":repeat: ターン数の上限に達したため停止しました。このスレッドで続きを指示するか、指示を分割してください。"
+	case backend.FailureRateLimited, backend.FailureOverloaded, backend.FailureNetwork:
+		msg := fmt.Sprintf(":hourglass: %sのため完了できませんでした", failureLabel(kind))
+		if retries > 0 {
+			msg += fmt.Sprintf("（%d回再試行）", retries)
+		}
+		return msg + "。しばらくしてからこのスレッドで再度指示してください。"
+	}
+	return fmt.Sprintf(":x: Claude実行エラー: %s", err)
+}
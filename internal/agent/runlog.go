@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+)
+
+// runRecording captures a run's transcript and the workspace changes it made.
+type runRecording struct {
+	run     *runstore.Run
+	workDir string
+	baseSHA string // HEAD before the run, for the diff
+}
+
+// startRecording begins recording a run. It returns nil if recording is
+// disabled or could not be started; a nil recording is safe to use.
+func (a *Agent) startRecording(taskID, workDir string) *runRecording {
+	if a.runs == nil {
+		return nil
+	}
+	run, err := a.runs.Create(taskID)
+	if err != nil {
+		a.logger.Error("failed to start run recording", "error", err, "task_id", taskID)
+		return nil
+	}
+	return &runRecording{
+		run:     run,
+		workDir: workDir,
+		baseSHA: strings.TrimSpace(gitOutput(workDir, "rev-parse", "HEAD")),
+	}
+}
+
+// transcript is where claude's raw output should be copied, or nil.
+func (rr *runRecording) transcript() io.Writer {
+	if rr == nil {
+		return nil
+	}
+	return rr.run.Stream()
+}
+
+// stderr is where claude's stderr should be copied, or nil.
+func (rr *runRecording) stderr() io.Writer {
+	if rr == nil {
+		return nil
+	}
+	return rr.run.Stderr()
+}
+
+// finishRecording saves the diff and metadata of a finished run.
+func (a *Agent) finishRecording(rr *runRecording, entry ledger.Entry) {
+	if rr == nil {
+		return
+	}
+	if rr.baseSHA != "" {
+		if err := rr.run.WriteDiff(gitOutput(rr.workDir, "diff", rr.baseSHA)); err != nil {
+			a.logger.Error("failed to save run diff", "error", err, "task_id", entry.TaskID)
+		}
+	}
+	if rr.run.Truncated() {
+		a.logger.Warn("run recording truncated at size limit", "task_id", entry.TaskID)
+	}
+	if err := rr.run.Finish(entry); err != nil {
+		a.logger.Error("failed to save run recording", "error", err, "task_id", entry.TaskID)
+	}
+}
+
+// runURL returns the web UI page of a run, or "" if the UI is not configured.
+func (a *Agent) runURL(taskID string) string {
+	if a.runs == nil || a.webBaseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/ui/runs/%s", strings.TrimRight(a.webBaseURL, "/"), taskID)
+}
+
+// gitOutput runs git in dir and returns its output, or "" on error.
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// handleTranscript uploads the raw stream-json transcript (and stderr, if any)
+// of the latest run in the thread.
+func (a *Agent) handleTranscript(ctx context.Context, channel, threadTS string) {
+	if a.runs == nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, ":information_source: 実行の記録が無効になっています。")
+		return
+	}
+
+	run, err := a.runs.LastInThread(threadTS)
+	if errors.Is(err, runstore.ErrNotFound) {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, ":information_source: このスレッドに記録された実行はありません。")
+		return
+	}
+	if err != nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf(":x: 実行記録の読み込みに失敗しました: %s", err))
+		return
+	}
+
+	transcript, err := readRunOutput(a.runs.OpenStream, run.TaskID)
+	if err != nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf(":x: トランスクリプトの読み込みに失敗しました: %s", err))
+		return
+	}
+	if transcript == "" {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, ":information_source: 直近の実行のトランスクリプトは空です。")
+		return
+	}
+	if err := a.slackClient.UploadFile(ctx, channel, threadTS,
+		fmt.Sprintf("transcript-%s.jsonl", run.TaskID), "トランスクリプト (stream-json)", transcript); err != nil {
+		a.logger.Error("failed to upload transcript", "error", err, "task_id", run.TaskID)
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf(":warning: トランスクリプトのアップロードに失敗しました: %s", err))
+		return
+	}
+
+	stderr, err := readRunOutput(a.runs.OpenStderr, run.TaskID)
+	if err != nil || strings.TrimSpace(stderr) == "" {
+		return
+	}
+	if err := a.slackClient.UploadFile(ctx, channel, threadTS,
+		fmt.Sprintf("stderr-%s.log", run.TaskID), "stderr", stderr); err != nil {
+		a.logger.Error("failed to upload stderr", "error", err, "task_id", run.TaskID)
+	}
+}
+
+func readRunOutput(open func(string) (io.ReadCloser, error), taskID string) (string, error) {
+	r, err := open(taskID)
+	if errors.Is(err, runstore.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// interruptedTask is a run that was cancelled by a shutdown and can be resumed
+// after restart with the `resume` command.
+type interruptedTask struct {
+	TaskID          string           `json:"task_id"`
+	Channel         string           `json:"channel"`
+	ThreadTS        string           `json:"thread_ts"`
+	User            string           `json:"user"`
+	Repository      string           `json:"repository"`
+	Related         []string         `json:"related,omitempty"` // multi-repository tasks
+	Mode            domain.AgentMode `json:"mode"`
+	Prompt          string           `json:"prompt"`
+	ClaudeSessionID string           `json:"claude_session_id,omitempty"`
+	InterruptedAt   time.Time        `json:"interrupted_at"`
+
+	// Options are those of a task that was itself resumed with resumePrompt,
+	// whose own options are in an earlier prompt.
+	Options *domain.InlineOptions `json:"options,omitempty"`
+}
+
+// resumePrompt is sent to Claude when resuming an interrupted session.
+const resumePrompt = "The previous run was interrupted by a server restart. Check the current state of the repository and continue the task from where you left off."
+
+// Shutdown stops accepting new tasks, notifies the threads of running tasks and
+// waits up to grace for them to finish. Tasks still running after that are
+// cancelled and saved as interrupted so they can be resumed after restart.
+func (a *Agent) Shutdown(grace time.Duration) {
+	ctx := context.Background()
+
+	a.tasksMu.Lock()
+	a.draining = true
+	a.tasksMu.Unlock()
+
+	// Persist interrupted tasks (including restored ones not yet resumed) on the way out
+	defer func() {
+		if err := a.saveInterrupted(); err != nil {
+			a.logger.Error("failed to save interrupted tasks", "error", err)
+		}
+	}()
+
+	running := a.activeTasks()
+	a.logger.Info("shutting down agent", "running_tasks", len(running), "grace_period", grace.String())
+	for _, t := range running {
+		a.updateMessage(ctx, t.Session, fmt.Sprintf(":construction: サーバーを再起動します。実行中のタスクの完了を最大%s待ちます。", formatDuration(grace)))
+	}
+
+	// Wait even when no task is registered yet: runs that passed beginTask may
+	// still be looking up the requester or checking budgets
+	if a.waitInflight(grace) {
+		a.logger.Info("all tasks finished before shutdown")
+		a.baseCancel()
+		return
+	}
+
+	// Cancel the remaining runs; runClaude records them as interrupted
+	remaining := a.activeTasks()
+	a.logger.Warn("grace period expired, interrupting tasks", "remaining_tasks", len(remaining))
+	a.baseCancel()
+	if !a.waitInflight(30 * time.Second) {
+		a.logger.Error("tasks did not stop after cancellation")
+	}
+}
+
+// waitInflight waits for all in-flight runs to finish. Returns false on timeout.
+func (a *Agent) waitInflight(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		a.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// markInterrupted records a run cancelled by shutdown.
+func (a *Agent) markInterrupted(t *activeTask) {
+	// A resumed task interrupted before Claude reported its session continues the same one
+	sessionID := t.ClaudeSessionID()
+	if sessionID == "" {
+		sessionID = t.Request.ResumeSessionID
+	}
+	a.tasksMu.Lock()
+	defer a.tasksMu.Unlock()
+	a.interrupted[t.ID] = interruptedTask{
+		TaskID:          t.ID,
+		Channel:         t.Session.Channel,
+		ThreadTS:        t.Session.ThreadTS,
+		User:            t.Request.User,
+		Repository:      t.Repository,
+		Related:         t.Related,
+		Mode:            t.Mode,
+		Prompt:          t.Request.Prompt,
+		ClaudeSessionID: sessionID,
+		InterruptedAt:   time.Now(),
+		Options:         t.Request.Options,
+	}
+}
+
+func (a *Agent) saveInterrupted() error {
+	if a.interruptedPath == "" {
+		return nil
+	}
+
+	a.tasksMu.Lock()
+	tasks := make([]interruptedTask, 0, len(a.interrupted))
+	for _, t := range a.interrupted {
+		tasks = append(tasks, t)
+	}
+	a.tasksMu.Unlock()
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.interruptedPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(a.interruptedPath, data, 0o644)
+}
+
+// RestoreInterrupted loads tasks interrupted by the previous shutdown, recreates
+// their sessions and tells each thread how to resume.
+func (a *Agent) RestoreInterrupted() {
+	ctx := context.Background()
+
+	if a.interruptedPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(a.interruptedPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to read interrupted tasks", "error", err)
+		return
+	}
+
+	var tasks []interruptedTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		a.logger.Error("failed to parse interrupted tasks", "error", err)
+		return
+	}
+
+	for _, t := range tasks {
+		repo := domain.FindRepository(a.repositories, t.Repository)
+		if repo == nil {
+			a.logger.Warn("skipping interrupted task for unknown repository", "repository", t.Repository, "thread", t.ThreadTS)
+			continue
+		}
+
+		session := domain.NewSession(t.Channel, t.ThreadTS, repo)
+		session.SetMode(t.Mode)
+		if len(t.Related) > 0 {
+			related := make([]*domain.Repository, 0, len(t.Related))
+			for _, key := range t.Related {
+				if r := domain.FindRepository(a.repositories, key); r != nil {
+					related = append(related, r)
+				}
+			}
+			session.SetRepositories(repo, related)
+		}
+
+		a.mu.Lock()
+		a.sessions[t.ThreadTS] = session
+		a.mu.Unlock()
+
+		a.tasksMu.Lock()
+		a.interrupted[t.TaskID] = t
+		a.tasksMu.Unlock()
+
+		a.slackClient.PostThreadMessage(ctx, t.Channel, t.ThreadTS,
+			":arrows_counterclockwise: サーバーが再起動しました。中断されたタスクを再開するには、このスレッドで `resume` と送信してください。")
+	}
+
+	// Restored tasks live in memory from now on; the file is rewritten on the next shutdown
+	if err := os.Remove(a.interruptedPath); err != nil {
+		a.logger.Warn("failed to remove interrupted tasks file", "error", err)
+	}
+	a.logger.Info("restored interrupted tasks", "count", len(tasks))
+}
+
+// handleResume resumes the oldest task interrupted in this thread, if any.
+// Further interrupted tasks of the thread are resumed by sending `resume` again.
+func (a *Agent) handleResume(ctx context.Context, session *domain.Session, user string) {
+	a.tasksMu.Lock()
+	var t interruptedTask
+	ok, remaining := false, 0
+	for _, it := range a.interrupted {
+		if it.ThreadTS != session.ThreadTS {
+			continue
+		}
+		if !ok || it.InterruptedAt.Before(t.InterruptedAt) || (it.InterruptedAt.Equal(t.InterruptedAt) && it.TaskID < t.TaskID) {
+			t, ok = it, true
+		}
+		remaining++
+	}
+	if ok {
+		delete(a.interrupted, t.TaskID)
+		remaining--
+	}
+	a.tasksMu.Unlock()
+
+	if !ok {
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
+			":information_source: このスレッドに中断されたタスクはありません。")
+		return
+	}
+
+	if session.Running() {
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
+			":warning: 既に実行中です。完了後にもう一度 `resume` と送信してください。")
+		a.tasksMu.Lock()
+		a.interrupted[t.TaskID] = t
+		a.tasksMu.Unlock()
+		return
+	}
+
+	if remaining > 0 {
+		a.slackClient.PostThreadMessage(ctx, session.Channel, session.ThreadTS,
+			fmt.Sprintf(":information_source: このスレッドには他に中断されたタスクが%d件あります。このタスクの完了後に `resume` と送信すると再開できます。", remaining))
+	}
+
+	// The run stays the requester's (commit author, budget, ledger), whoever resumes it
+	req := taskRequest{User: t.User, Prompt: t.Prompt}
+	if req.User == "" {
+		req.User = user
+	}
+	if t.ClaudeSessionID != "" {
+		// The original options (timeout=, model=, repos=) still apply
+		req.Options = t.Options
+		if opts, _, err := domain.ParseInlineOptions(t.Prompt); err == nil && req.Options == nil {
+			req.Options = &opts
+		}
+		req.Prompt = resumePrompt
+		req.ResumeSessionID = t.ClaudeSessionID
+	}
+	session.SetMode(t.Mode)
+	a.logger.Info("resuming interrupted task", "thread", session.ThreadTS, "task_id", t.TaskID, "claude_session", t.ClaudeSessionID)
+	a.continueSession(ctx, session, req)
+}
@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/signing"
+)
+
+// checkSigning makes sure commits in repo can be signed before a run starts
+// making them. It is a no-op for repositories without signing.
+func (a *Agent) checkSigning(ctx context.Context, repo *domain.Repository) error {
+	cfg, _ := domain.LookupRepo(a.signing, repo.Key())
+	return signing.Check(ctx, cfg)
+}
+
+func signingErrorMessage(repo *domain.Repository, err error) string {
+	return fmt.Sprintf(":closed_lock_with_key: %s ではコミットへの署名が必要ですが、署名を確認できませんでした。管理者に署名鍵の設定を確認してもらってください。\n```\n%s\n```", repo.Key(), err)
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// activeTask is a Claude run currently in flight.
+type activeTask struct {
+	ID         string
+	Session    *domain.Session
+	Request    taskRequest
+	Repository string
+	Related    []string // other repositories of a multi-repository task
+	Mode       domain.AgentMode
+	StartedAt  time.Time
+	Cancel     context.CancelFunc
+
+	mu              sync.Mutex
+	claudeSessionID string
+	queued          bool   // waiting for a scheduler slot
+	preparing       bool   // updating its checkouts, see prepareWorkspaces
+	currentTool     string // summary of the latest tool use
+}
+
+func (t *activeTask) setClaudeSessionID(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.claudeSessionID = id
+}
+
+func (t *activeTask) ClaudeSessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.claudeSessionID
+}
+
+func (t *activeTask) setQueued(queued bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queued = queued
+}
+
+func (t *activeTask) Queued() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.queued
+}
+
+func (t *activeTask) setPreparing(preparing bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.preparing = preparing
+}
+
+func (t *activeTask) Preparing() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.preparing
+}
+
+func (t *activeTask) setCurrentTool(summary string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.currentTool = summary
+}
+
+func (t *activeTask) CurrentTool() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.currentTool
+}
+
+// startRun runs Claude for the session in the background.
+func (a *Agent) startRun(ctx context.Context, session *domain.Session, req taskRequest) {
+	a.started.Add(1)
+	go func() {
+		defer a.started.Done()
+		a.runClaude(ctx, session, req)
+	}()
+}
+
+// Wait blocks until every run started so far has finished, or timeout passes.
+// It returns false on timeout. Offline replays use it to know when the Slack
+// output is complete; the server uses Shutdown instead.
+func (a *Agent) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		a.started.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// beginTask registers a new in-flight run. It returns false if the agent is
+// shutting down and no new tasks are accepted.
+func (a *Agent) beginTask() bool {
+	a.tasksMu.Lock()
+	defer a.tasksMu.Unlock()
+	if a.draining {
+		return false
+	}
+	a.inflight.Add(1)
+	return true
+}
+
+// endTask marks an in-flight run as finished.
+func (a *Agent) endTask() {
+	a.inflight.Done()
+}
+
+func (a *Agent) registerTask(t *activeTask) {
+	a.tasksMu.Lock()
+	defer a.tasksMu.Unlock()
+	a.tasks[t.ID] = t
+}
+
+func (a *Agent) unregisterTask(id string) {
+	a.tasksMu.Lock()
+	defer a.tasksMu.Unlock()
+	delete(a.tasks, id)
+}
+
+// activeTasks returns a snapshot of the running tasks.
+func (a *Agent) activeTasks() []*activeTask {
+	a.tasksMu.Lock()
+	defer a.tasksMu.Unlock()
+	tasks := make([]*activeTask, 0, len(a.tasks))
+	for _, t := range a.tasks {
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
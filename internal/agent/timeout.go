@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// startTimeoutWarning posts a warning to the thread shortly before the task's deadline.
+// The returned function cancels the warning.
+func (a *Agent) startTimeoutWarning(ctx context.Context, session *domain.Session, timeout time.Duration) func() {
+	warning := a.timeouts.Warning
+	if warning <= 0 || warning >= timeout {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(timeout-warning, func() {
+		a.updateMessage(ctx, session, fmt.Sprintf(":hourglass: あと約%sでタイムアウトします（上限: %s）。必要に応じて `stop` で停止し、指示を分割してください。",
+			formatDuration(warning), formatDuration(timeout)))
+	})
+	return func() { timer.Stop() }
+}
+
+// timedOutMessage builds the message for a run that hit its deadline, including partial results.
+func timedOutMessage(timeout time.Duration, partialText, summary string) string {
+	msg := fmt.Sprintf(":alarm_clock: タイムアウトしました（上限: %s）。より長い時間が必要な場合は `timeout=90m` のように指定してください。", formatDuration(timeout))
+	if partialText != "" {
+		msg += "\n\n*途中までの結果:*\n" + formatForSlack(partialText)
+	}
+	if summary != "" {
+		msg += "\n\n" + summary
+	}
+	return msg
+}
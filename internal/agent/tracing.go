@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
+)
+
+// startDispatchSpan starts a span covering the handling of one Slack message.
+func startDispatchSpan(ctx context.Context, cmd domain.Command, threadTS string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "agent.dispatch", trace.WithAttributes(
+		attribute.String("command", cmd.String()),
+		attribute.String("slack.thread_ts", threadTS),
+	))
+}
+
+// toolSpans tracks one span per Claude tool use, keyed by tool use ID.
+type toolSpans struct {
+	ctx   context.Context
+	spans map[string]trace.Span
+}
+
+func newToolSpans(ctx context.Context) *toolSpans {
+	return &toolSpans{ctx: ctx, spans: make(map[string]trace.Span)}
+}
+
+func (t *toolSpans) start(id, name, summary string) {
+	_, span := tracing.Tracer().Start(t.ctx, "claude.tool "+name, trace.WithAttributes(
+		attribute.String("tool.name", name),
+		attribute.String("tool.id", id),
+		attribute.String("tool.summary", summary),
+	))
+	t.spans[id] = span
+}
+
+func (t *toolSpans) end(id string, isError bool) {
+	span, ok := t.spans[id]
+	if !ok {
+		return
+	}
+	if isError {
+		span.SetStatus(codes.Error, "tool returned an error")
+	}
+	span.End()
+	delete(t.spans, id)
+}
+
+// endAll ends tools that never reported a result (e.g. the run was cancelled).
+func (t *toolSpans) endAll() {
+	for id, span := range t.spans {
+		span.End()
+		delete(t.spans, id)
+	}
+}
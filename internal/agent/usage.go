@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+)
+
+// defaultUsageDays is the report period when none is given.
+const defaultUsageDays = 7
+
+// prURLRe matches GitHub (/pull/N), GitLab (/-/merge_requests/N) and Gitea
+// (/pulls/N) pull request URLs.
+var prURLRe = regexp.MustCompile(`https://[^\s<>|()]+/(?:pulls?|-/merge_requests)/\d+`)
+
+// extractPRURL returns the last pull request (or merge request) URL mentioned
+// in Claude's output.
+func extractPRURL(text string) string {
+	matches := prURLRe.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1]
+}
+
+// observeRun updates the Prometheus metrics for a finished run.
+func (a *Agent) observeRun(repo string, outcome ledger.Outcome, elapsed time.Duration, cost float64) {
+	metrics.RunsCompleted.Inc(repo, string(outcome))
+	// Stops, budget stops and shutdown interruptions are normal operation
+	if outcome == ledger.OutcomeError || outcome == ledger.OutcomeTimedOut {
+		metrics.RunsFailed.Inc(repo)
+	}
+	metrics.RunDuration.Observe(elapsed.Seconds(), repo)
+	metrics.RunCost.Observe(cost, repo)
+	metrics.CostTotal.Add(cost, repo)
+}
+
+func (a *Agent) recordUsage(entry ledger.Entry) {
+	if a.ledger == nil {
+		return
+	}
+	if err := a.ledger.Append(entry); err != nil {
+		a.logger.Error("failed to record usage", "error", err, "task_id", entry.TaskID)
+	}
+}
+
+// handleUsage posts usage totals for the last N days and uploads the raw entries as CSV.
+func (a *Agent) handleUsage(ctx context.Context, channel, threadTS string, days int) {
+	if a.ledger == nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, ":information_source: 利用状況の記録が無効になっています。")
+		return
+	}
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(days - 1))
+	entries, err := a.ledger.Since(since)
+	if err != nil {
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf(":x: 利用状況の読み込みに失敗しました: %s", err))
+		return
+	}
+
+	report := ledger.Summarize(entries)
+	a.slackClient.PostThreadMessage(ctx, channel, threadTS, formatUsageReport(report, days))
+
+	if len(entries) == 0 {
+		return
+	}
+
+	var csvBuf strings.Builder
+	if err := ledger.WriteCSV(&csvBuf, entries); err != nil {
+		a.logger.Error("failed to build usage csv", "error", err)
+		return
+	}
+	filename := fmt.Sprintf("claude-usage-%s.csv", now.Format("20060102"))
+	if err := a.slackClient.UploadFile(ctx, channel, threadTS, filename, "利用状況 (CSV)", csvBuf.String()); err != nil {
+		a.logger.Error("failed to upload usage csv", "error", err)
+		a.slackClient.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf(":warning: CSVのアップロードに失敗しました: %s", err))
+	}
+}
+
+func formatUsageReport(r ledger.Report, days int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":bar_chart: *利用状況（過去%d日間）*\n", days))
+	sb.WriteString(fmt.Sprintf("合計: %d 回  |  $%.2f\n", r.Runs, r.Cost))
+
+	if r.Runs == 0 {
+		sb.WriteString("\n記録された実行はありません。")
+		return sb.String()
+	}
+
+	sb.WriteString("\n*ユーザー別:*\n")
+	for _, t := range r.ByUser {
+		sb.WriteString(fmt.Sprintf("• <@%s>: %d 回  |  $%.2f\n", t.Key, t.Runs, t.Cost))
+	}
+
+	sb.WriteString("\n*リポジトリ別:*\n")
+	for _, t := range r.ByRepo {
+		sb.WriteString(fmt.Sprintf("• %s: %d 回  |  $%.2f\n", t.Key, t.Runs, t.Cost))
+	}
+
+	sb.WriteString("\n*日別:*\n")
+	for _, t := range r.ByDay {
+		sb.WriteString(fmt.Sprintf("• %s: %d 回  |  $%.2f\n", t.Key, t.Runs, t.Cost))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/workspace"
+)
+
+// maxDirtyFilesShown caps the changed files listed when a dirty checkout is refused.
+const maxDirtyFilesShown = 10
+
+// prepareWorkspaces brings the checkouts of a task's repositories up to date
+// before its run and returns the commit the run starts from in the first one.
+// Preparation is serialized per checkout: the task holds the locks of all its
+// checkouts until every one is prepared, so another task never starts in a
+// checkout that is being stashed or switched, and waits instead. A checkout is
+// not prepared ("" and no error) when preparation is disabled, when the run
+// resumes earlier work, or when another task is running in it.
+func (a *Agent) prepareWorkspaces(ctx, runCtx context.Context, session *domain.Session, task *activeTask, repos []*domain.Repository, resuming bool) (string, error) {
+	defer task.setPreparing(false)
+	if a.workspace == nil {
+		return "", nil
+	}
+
+	keys := make([]string, len(repos))
+	for i, r := range repos {
+		keys[i] = r.Key()
+	}
+	unlock, err := a.checkouts.lock(runCtx, keys)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if resuming {
+		return "", nil
+	}
+
+	var baseSHA string
+	for i, r := range repos {
+		sha, err := a.prepareWorkspace(ctx, runCtx, session, task, a.backends[r.Key()].WorkDir(), r)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			baseSHA = sha
+		}
+	}
+	return baseSHA, nil
+}
+
+// prepareWorkspace brings one checkout up to date, unless another task is
+// running in it. The caller holds the checkout's lock.
+func (a *Agent) prepareWorkspace(ctx, runCtx context.Context, session *domain.Session, task *activeTask, workDir string, repo *domain.Repository) (string, error) {
+	if a.repoBusy(task, repo.Key()) {
+		a.logger.Info("other task running in workspace, not preparing it", "task_id", task.ID, "repository", repo.Key())
+		a.updateMessage(ctx, session, fmt.Sprintf(":information_source: %s で他のタスクが実行中のため、ワークスペースを更新せずに開始します。", repo.Key()))
+		return "", nil
+	}
+
+	prepared, err := a.workspace.Prepare(runCtx, workDir, repo.DefaultBranch, "task "+task.ID)
+	if err != nil {
+		return "", err
+	}
+	if prepared.Stashed != "" {
+		a.updateMessage(ctx, session, fmt.Sprintf(":package: ワークスペースに残っていた未コミットの変更を stash に退避しました（`%s`）", prepared.Stashed))
+	}
+	if prepared.Backup != "" {
+		a.updateMessage(ctx, session, fmt.Sprintf(":package: `%s` の未プッシュのコミットをブランチ `%s` に退避しました", prepared.Branch, prepared.Backup))
+	}
+	a.updateMessage(ctx, session, fmt.Sprintf(":seedling: %s の `%s` を最新化しました（base: `%s`）", repo.Key(), prepared.Branch, shortSHA(prepared.BaseSHA)))
+	return prepared.BaseSHA, nil
+}
+
+// checkoutLocks serializes workspace preparation per checkout
+// (domain.RepoKeyOf). The zero value is ready to use.
+type checkoutLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{} // a buffered channel of one per checkout
+}
+
+// lock acquires the locks of the checkouts of repoKeys, in a fixed order so
+// tasks sharing several checkouts can't deadlock, or fails when ctx is done.
+// The returned function releases them.
+func (l *checkoutLocks) lock(ctx context.Context, repoKeys []string) (func(), error) {
+	seen := make(map[string]bool)
+	var checkouts []string
+	for _, key := range repoKeys {
+		if c := domain.RepoKeyOf(key); !seen[c] {
+			seen[c] = true
+			checkouts = append(checkouts, c)
+		}
+	}
+	sort.Strings(checkouts)
+
+	var held []chan struct{}
+	unlock := func() {
+		for _, ch := range held {
+			<-ch
+		}
+	}
+	for _, c := range checkouts {
+		ch := l.get(c)
+		select {
+		case ch <- struct{}{}:
+			held = append(held, ch)
+		case <-ctx.Done():
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+	return unlock, nil
+}
+
+func (l *checkoutLocks) get(checkout string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.locks == nil {
+		l.locks = make(map[string]chan struct{})
+	}
+	ch, ok := l.locks[checkout]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		l.locks[checkout] = ch
+	}
+	return ch
+}
+
+// ensureCheckout clones the repository's checkout if it is missing and checks
+// its origin. It is a no-op when bootstrapping is disabled.
+func (a *Agent) ensureCheckout(ctx context.Context, repo *domain.Repository) error {
+	if a.bootstrap == nil {
+		return nil
+	}
+	return a.bootstrap.Ensure(ctx, repo)
+}
+
+// repoBusy reports whether another task is running (not queued or still
+// preparing its checkouts) in the checkout of repoKey, including other targets
+// of the same monorepo and the related repositories of multi-repository tasks.
+func (a *Agent) repoBusy(task *activeTask, repoKey string) bool {
+	checkout := domain.RepoKeyOf(repoKey)
+	for _, t := range a.activeTasks() {
+		if t.ID == task.ID || t.Queued() || t.Preparing() {
+			continue
+		}
+		for _, key := range append([]string{t.Repository}, t.Related...) {
+			if domain.RepoKeyOf(key) == checkout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func workspaceErrorMessage(err error) string {
+	var unpushed *workspace.UnpushedError
+	if errors.As(err, &unpushed) {
+		commits := unpushed.Commits
+		more := ""
+		if len(commits) > maxDirtyFilesShown {
+			more = fmt.Sprintf("\n…ほか %d 件", len(commits)-maxDirtyFilesShown)
+			commits = commits[:maxDirtyFilesShown]
+		}
+		return fmt.Sprintf(":no_entry: ワークスペースの `%s` に未プッシュのコミットがあるため実行できません。管理者が確認して片付けてください。\n```\n%s\n```%s",
+			unpushed.Branch, strings.Join(commits, "\n"), more)
+	}
+
+	var dirty *workspace.DirtyError
+	if !errors.As(err, &dirty) {
+		return fmt.Sprintf(":x: ワークスペースの更新に失敗しました: %s", err)
+	}
+
+	files := dirty.Files
+	more := ""
+	if len(files) > maxDirtyFilesShown {
+		more = fmt.Sprintf("\n…ほか %d 件", len(files)-maxDirtyFilesShown)
+		files = files[:maxDirtyFilesShown]
+	}
+	return fmt.Sprintf(":no_entry: ワークスペースに未コミットの変更があるため実行できません。管理者が確認して片付けてください。\n```\n%s\n```%s",
+		strings.Join(files, "\n"), more)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
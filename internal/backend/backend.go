@@ -0,0 +1,150 @@
+// Package backend defines how the agent drives a coding agent. The Claude CLI
+// is one implementation; others (an API loop with local tools, another CLI)
+// only need to translate their output into Events.
+package backend
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Backend runs tasks in one repository checkout.
+type Backend interface {
+	// Name identifies the implementation in config and logs (e.g. "claude-cli").
+	Name() string
+
+	// WorkDir is the repository checkout tasks run in.
+	WorkDir() string
+
+	// Start begins a task. Cancelling ctx or calling Execution.Cancel stops it.
+	Start(ctx context.Context, task Task) (Execution, error)
+}
+
+// Task is a request to a backend.
+type Task struct {
+	Prompt          string
+	Mode            domain.AgentMode
+	ResumeSessionID string // continue this backend session (from EventSession) if set
+	MaxTurns        int    // 0 = backend default
+	Model           string // empty = backend default
+	FallbackModel   string // switch to this model when Model is overloaded, if supported
+
+	// Related are further repositories the task may change besides the one
+	// the backend works in (multi-repository tasks).
+	Related []RelatedRepo
+
+	// Requester is the git identity of the Slack user who asked for the
+	// task, for commit attribution; nil if unknown.
+	Requester *domain.GitIdentity
+
+	// Transcript and Stderr, if set, receive a copy of the backend's raw
+	// output and diagnostics. Backends without such output ignore them.
+	Transcript io.Writer
+	Stderr     io.Writer
+}
+
+// RelatedRepo is an additional repository of a multi-repository task.
+type RelatedRepo struct {
+	Key           string // owner/name
+	Dir           string // checkout the backend is given access to
+	DefaultBranch string
+	GitHost       string // githost name; "" means GitHub
+}
+
+// Execution is a running task.
+type Execution interface {
+	// Events delivers progress in order and is closed when the task ends.
+	// It must be drained.
+	Events() <-chan Event
+
+	// Cancel stops the task. Wait then returns context.Canceled.
+	Cancel()
+
+	// Wait blocks until the task ends and returns its result, which may be
+	// set (partially) even when an error is returned. Failures reported by
+	// the backend are returned as *Error so they can be retried or explained.
+	Wait() (*Result, error)
+}
+
+// EventType is the kind of a progress event.
+type EventType int
+
+const (
+	EventSession    EventType = iota // the backend session started; SessionID can be resumed
+	EventTurn                        // a new model turn started; carries its Usage
+	EventText                        // assistant text
+	EventToolUse                     // a tool was invoked
+	EventToolResult                  // a tool returned
+	EventDone                        // the task finished; carries the Result
+)
+
+// Event is a normalized progress event.
+type Event struct {
+	Type        EventType
+	SessionID   string         // EventSession
+	Model       string         // EventSession, EventTurn: model in use, if reported
+	Text        string         // EventText
+	ToolName    string         // EventToolUse
+	ToolID      string         // EventToolUse, EventToolResult
+	ToolInput   map[string]any // EventToolUse
+	ToolSummary string         // EventToolUse: short description for Slack (ToolName if empty)
+	IsError     bool           // EventToolResult
+	Usage       *Usage         // EventTurn
+	Result      *Result        // EventDone
+}
+
+// Usage is the token usage of one model turn.
+type Usage struct {
+	InputTokens              int
+	OutputTokens             int
+	CacheCreationInputTokens int
+	CacheReadInputTokens     int
+}
+
+// Result is the outcome of a task as reported by the backend.
+type Result struct {
+	SessionID string
+	Text      string // final answer or error message
+	IsError   bool
+	Cost      float64 // USD; 0 if the backend does not report it
+	Turns     int     // 0 if the backend does not report it
+	Duration  time.Duration
+}
+
+// Go runs fn in the background as an Execution. fn reports progress with
+// emit; it must return once ctx is cancelled.
+func Go(ctx context.Context, fn func(ctx context.Context, emit func(Event)) (*Result, error)) Execution {
+	ctx, cancel := context.WithCancel(ctx)
+	e := &execution{
+		events: make(chan Event, 64),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(e.done)
+		defer cancel()
+		e.result, e.err = fn(ctx, func(evt Event) { e.events <- evt })
+		close(e.events)
+	}()
+	return e
+}
+
+type execution struct {
+	events chan Event
+	cancel context.CancelFunc
+	done   chan struct{}
+	result *Result
+	err    error
+}
+
+func (e *execution) Events() <-chan Event { return e.events }
+
+func (e *execution) Cancel() { e.cancel() }
+
+func (e *execution) Wait() (*Result, error) {
+	<-e.done
+	return e.result, e.err
+}
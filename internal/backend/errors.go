@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"context"
+	"errors"
+)
+
+// FailureKind classifies why a task failed.
+type FailureKind string
+
+const (
+	FailureNone        FailureKind = ""
+	FailureRateLimited FailureKind = "rate_limited"
+	FailureOverloaded  FailureKind = "overloaded"
+	FailureNetwork     FailureKind = "network"
+	FailureAuth        FailureKind = "auth"      // credentials expired or invalid
+	FailureMaxTurns    FailureKind = "max_turns" // stopped at the turn limit
+	FailureCancelled   FailureKind = "cancelled"
+	FailureUnknown     FailureKind = "unknown"
+)
+
+// Retryable reports whether the failure is transient and the task may succeed
+// when resumed after a while.
+func (k FailureKind) Retryable() bool {
+	switch k {
+	case FailureRateLimited, FailureOverloaded, FailureNetwork:
+		return true
+	}
+	return false
+}
+
+// Error is a classified task failure returned by Execution.Wait.
+type Error struct {
+	Kind FailureKind
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// KindOf classifies err. Cancelled contexts count as FailureCancelled and
+// unclassified errors as FailureUnknown.
+func KindOf(err error) FailureKind {
+	var be *Error
+	switch {
+	case err == nil:
+		return FailureNone
+	case errors.As(err, &be):
+		return be.Kind
+	case errors.Is(err, context.Canceled):
+		return FailureCancelled
+	}
+	return FailureUnknown
+}
@@ -0,0 +1,354 @@
+package budget
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Limits holds the configured budgets. A zero value means "unlimited".
+type Limits struct {
+	TaskCost      float64            // max USD per task
+	TaskTurns     int                // max turns per task (passed to claude --max-turns)
+	UserDailyCost float64            // max USD per Slack user per day
+	RepoDailyCost float64            // max USD per repository (checkout, shared by its targets) per day
+	RepoOverrides map[string]float64 // per-repository daily cost, key: owner/name or owner/name#target
+	DailyCost     float64            // max USD across all users and repositories per day
+	WarnRatio     float64            // post a warning when this fraction of a budget is used
+
+	// Prices used to estimate cost mid-stream (USD per million tokens): the
+	// ModelPrices entry of the model, or the default prices for other models.
+	InputPricePerMTok  float64
+	OutputPricePerMTok float64
+	ModelPrices        map[string]Price // key: model name, or a part of it such as "opus"
+}
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// DefaultModelPrices are the prices of the Claude model families, used when
+// no prices are configured. They follow the most expensive model of each
+// family, so estimates err on the high side.
+var DefaultModelPrices = map[string]Price{
+	"opus":   {Input: 15, Output: 75},
+	"sonnet": {Input: 3, Output: 15},
+	"haiku":  {Input: 1, Output: 5},
+}
+
+// Usage is the token usage reported for a single assistant turn.
+type Usage struct {
+	InputTokens              int
+	OutputTokens             int
+	CacheCreationInputTokens int
+	CacheReadInputTokens     int
+}
+
+// ExhaustedError is returned when a budget does not allow starting a new task.
+type ExhaustedError struct {
+	Scope string // "user", "repository" or "daily"
+	Limit float64
+	Spent float64
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("%s budget exhausted: spent $%.2f of $%.2f", e.Scope, e.Spent, e.Limit)
+}
+
+// Tracker accumulates spending per user, per repository and globally for the
+// current day. The estimated cost of running tasks counts against the budgets
+// until the task's actual cost is recorded.
+type Tracker struct {
+	mu       sync.Mutex
+	limits   Limits
+	day      string
+	users    map[string]float64
+	repos    map[string]float64 // key: checkout (owner/name without #target)
+	total    float64
+	inflight map[*TaskGuard]float64 // estimated cost so far of running tasks
+	now      func() time.Time
+}
+
+func NewTracker(limits Limits) *Tracker {
+	t := &Tracker{
+		limits:   limits,
+		inflight: make(map[*TaskGuard]float64),
+		now:      time.Now,
+	}
+	t.reset(t.today())
+	return t
+}
+
+// Limits returns the configured limits.
+func (t *Tracker) Limits() Limits {
+	return t.limits
+}
+
+func (t *Tracker) today() string {
+	return t.now().Format("2006-01-02")
+}
+
+func (t *Tracker) reset(day string) {
+	t.day = day
+	t.users = make(map[string]float64)
+	t.repos = make(map[string]float64)
+	t.total = 0
+}
+
+// rollover resets counters when the day changes. Caller must hold mu.
+func (t *Tracker) rollover() {
+	if day := t.today(); day != t.day {
+		t.reset(day)
+	}
+}
+
+// repoLimit returns the daily limit of a repository key, falling back to the
+// override of the whole repository for a target.
+func (t *Tracker) repoLimit(repo string) float64 {
+	if limit, ok := domain.LookupRepo(t.limits.RepoOverrides, repo); ok {
+		return limit
+	}
+	return t.limits.RepoDailyCost
+}
+
+// scopeUsage is the state of one limited budget.
+type scopeUsage struct {
+	scope string // "daily", "user" or "repository"
+	limit float64
+	spent float64 // recorded spending plus the estimated cost of running tasks
+}
+
+// usage returns the limited budgets a task of user in repos counts against.
+// Caller must hold mu.
+func (t *Tracker) usage(user string, repos []string) []scopeUsage {
+	var inflightTotal, inflightUser float64
+	inflightRepos := make(map[string]float64)
+	for g, cost := range t.inflight {
+		inflightTotal += cost
+		if g.user == user {
+			inflightUser += cost
+		}
+		for _, repo := range g.repos {
+			inflightRepos[domain.RepoKeyOf(repo)] += cost
+		}
+	}
+
+	var scopes []scopeUsage
+	if limit := t.limits.DailyCost; limit > 0 {
+		scopes = append(scopes, scopeUsage{"daily", limit, t.total + inflightTotal})
+	}
+	if limit := t.limits.UserDailyCost; limit > 0 {
+		scopes = append(scopes, scopeUsage{"user", limit, t.users[user] + inflightUser})
+	}
+	for _, repo := range repos {
+		if limit := t.repoLimit(repo); limit > 0 {
+			checkout := domain.RepoKeyOf(repo)
+			scopes = append(scopes, scopeUsage{"repository", limit, t.repos[checkout] + inflightRepos[checkout]})
+		}
+	}
+	return scopes
+}
+
+// Check returns an *ExhaustedError if the user, daily or any of the repositories'
+// budgets is used up.
+func (t *Tracker) Check(user string, repos ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	return t.check(user, repos)
+}
+
+// check is Check with mu held.
+func (t *Tracker) check(user string, repos []string) error {
+	for _, s := range t.usage(user, repos) {
+		if s.spent >= s.limit {
+			return &ExhaustedError{Scope: s.scope, Limit: s.limit, Spent: s.spent}
+		}
+	}
+	return nil
+}
+
+// TaskCostLimit returns the effective cost cap for a new task: the per-task limit,
+// lowered to whatever remains of the user, daily and repositories' budgets.
+// capped is false if there is no limit at all; a capped limit of 0 means
+// nothing remains.
+func (t *Tracker) TaskCostLimit(user string, repos ...string) (limit float64, capped bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	return t.taskCostLimit(user, repos)
+}
+
+// taskCostLimit is TaskCostLimit with mu held.
+func (t *Tracker) taskCostLimit(user string, repos []string) (limit float64, capped bool) {
+	limit, capped = t.limits.TaskCost, t.limits.TaskCost > 0
+	for _, s := range t.usage(user, repos) {
+		if remaining := s.limit - s.spent; !capped || remaining < limit {
+			limit, capped = remaining, true
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return limit, capped
+}
+
+// Record adds the cost of a finished task to the user and daily totals, and to
+// the total of each checkout the task ran in.
+func (t *Tracker) Record(user string, repos []string, cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+
+	t.users[user] += cost
+	for _, repo := range repos {
+		t.repos[domain.RepoKeyOf(repo)] += cost
+	}
+	t.total += cost
+}
+
+// SpentToday returns today's total spending.
+func (t *Tracker) SpentToday() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	return t.total
+}
+
+// PriceOf returns the prices of model: the ModelPrices entry named exactly
+// like it, else the longest entry contained in its name (e.g. "opus" for
+// "claude-opus-4-1"), else the default prices.
+func (l Limits) PriceOf(model string) Price {
+	if p, ok := l.ModelPrices[model]; ok {
+		return p
+	}
+	price, matched := Price{Input: l.InputPricePerMTok, Output: l.OutputPricePerMTok}, ""
+	for name, p := range l.ModelPrices {
+		if name != "" && strings.Contains(model, name) && len(name) > len(matched) {
+			price, matched = p, name
+		}
+	}
+	return price
+}
+
+// EstimateCost estimates the cost of a turn of model from its token usage.
+func (l Limits) EstimateCost(model string, u Usage) float64 {
+	price := l.PriceOf(model)
+	input := float64(u.InputTokens) +
+		float64(u.CacheCreationInputTokens)*1.25 +
+		float64(u.CacheReadInputTokens)*0.1
+	return input*price.Input/1e6 + float64(u.OutputTokens)*price.Output/1e6
+}
+
+// TaskGuard tracks a single running task against its cost and turn limits,
+// and the budgets shared with the other running tasks.
+type TaskGuard struct {
+	tracker  *Tracker
+	user     string
+	repos    []string
+	limits   Limits
+	maxCost  float64
+	maxTurns int
+
+	Turns  int
+	Cost   float64 // estimated cost so far
+	warned bool
+}
+
+// NewTaskGuard checks the budgets of a new task of user in repos and starts
+// counting its estimated cost against them. It returns an *ExhaustedError if a
+// budget is used up, counting the estimated cost of the tasks already running.
+// Release must be called when the task ends, before its cost is recorded.
+func (t *Tracker) NewTaskGuard(user string, repos ...string) (*TaskGuard, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+
+	if err := t.check(user, repos); err != nil {
+		return nil, err
+	}
+	// check refused any budget with nothing left, so a capped limit is positive
+	maxCost, _ := t.taskCostLimit(user, repos)
+	g := &TaskGuard{
+		tracker:  t,
+		user:     user,
+		repos:    repos,
+		limits:   t.limits,
+		maxCost:  maxCost,
+		maxTurns: t.limits.TaskTurns,
+	}
+	t.inflight[g] = 0
+	return g, nil
+}
+
+// Release stops counting the task's estimated cost against the budgets. It
+// is safe to call more than once.
+func (g *TaskGuard) Release() {
+	g.tracker.mu.Lock()
+	defer g.tracker.mu.Unlock()
+	delete(g.tracker.inflight, g)
+}
+
+// exhausted updates the estimated cost of g and reports whether one of its
+// budgets is now used up by it and the other running tasks.
+func (t *Tracker) exhausted(g *TaskGuard) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.inflight[g]; !ok {
+		return false
+	}
+	t.inflight[g] = g.Cost
+	return t.check(g.user, g.repos) != nil
+}
+
+// GuardStatus is the result of adding a turn to a TaskGuard.
+type GuardStatus int
+
+const (
+	GuardOK GuardStatus = iota
+	GuardWarn
+	GuardExceeded
+)
+
+// AddTurn records one assistant turn of model and reports whether a threshold
+// was crossed. GuardWarn is returned at most once per task.
+func (g *TaskGuard) AddTurn(model string, u Usage) GuardStatus {
+	g.Turns++
+	g.Cost += g.limits.EstimateCost(model, u)
+
+	if g.maxCost > 0 && g.Cost >= g.maxCost {
+		return GuardExceeded
+	}
+	if g.tracker.exhausted(g) {
+		return GuardExceeded
+	}
+	// claude --max-turns stops the run itself; we only cancel if it overshoots
+	if g.maxTurns > 0 && g.Turns > g.maxTurns {
+		return GuardExceeded
+	}
+
+	if !g.warned && g.limits.WarnRatio > 0 {
+		costWarn := g.maxCost > 0 && g.Cost >= g.maxCost*g.limits.WarnRatio
+		turnWarn := g.maxTurns > 0 && float64(g.Turns) >= float64(g.maxTurns)*g.limits.WarnRatio
+		if costWarn || turnWarn {
+			g.warned = true
+			return GuardWarn
+		}
+	}
+	return GuardOK
+}
+
+// MaxCost returns the task's cost cap (0 = unlimited).
+func (g *TaskGuard) MaxCost() float64 {
+	return g.maxCost
+}
+
+// MaxTurns returns the task's turn cap (0 = unlimited).
+func (g *TaskGuard) MaxTurns() int {
+	return g.maxTurns
+}
@@ -0,0 +1,131 @@
+package budget
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTaskCostLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		spent      float64 // recorded for user u1 in org/a before the call
+		repos      []string
+		wantLimit  float64
+		wantCapped bool
+	}{
+		{"unlimited", Limits{}, 5, []string{"org/a"}, 0, false},
+		{"task limit only", Limits{TaskCost: 2}, 5, []string{"org/a"}, 2, true},
+		{"daily remainder lowers the task limit", Limits{TaskCost: 2, DailyCost: 6}, 5, []string{"org/a"}, 1, true},
+		{"daily remainder without a task limit", Limits{DailyCost: 6}, 5, []string{"org/a"}, 1, true},
+		{"user remainder", Limits{TaskCost: 2, UserDailyCost: 5.5}, 5, []string{"org/a"}, 0.5, true},
+		{"exhausted", Limits{DailyCost: 5}, 5, []string{"org/a"}, 0, true},
+		{"negative remainder", Limits{TaskCost: 2, DailyCost: 4}, 5, []string{"org/a"}, 0, true},
+		{"an exhausted scope is not replaced by a larger later one", Limits{UserDailyCost: 5, RepoDailyCost: 100}, 5, []string{"org/a"}, 0, true},
+		{"lowest repository remainder", Limits{RepoDailyCost: 8, RepoOverrides: map[string]float64{"org/b": 3}}, 5, []string{"org/a", "org/b"}, 3, true},
+		{"override of the whole repository applies to targets", Limits{RepoOverrides: map[string]float64{"org/a": 7}}, 5, []string{"org/a#web"}, 2, true},
+		{"targets share the checkout's spending", Limits{RepoDailyCost: 6}, 5, []string{"org/a#api"}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTracker(tt.limits)
+			tr.Record("u1", []string{"org/a"}, tt.spent)
+			limit, capped := tr.TaskCostLimit("u1", tt.repos...)
+			if math.Abs(limit-tt.wantLimit) > 1e-9 || capped != tt.wantCapped {
+				t.Errorf("TaskCostLimit() = %v, %v; want %v, %v", limit, capped, tt.wantLimit, tt.wantCapped)
+			}
+		})
+	}
+}
+
+func TestNewTaskGuardCountsRunningTasks(t *testing.T) {
+	tr := NewTracker(Limits{DailyCost: 10, ModelPrices: map[string]Price{"sonnet": {Input: 0, Output: 1}}})
+	turn := Usage{OutputTokens: 1_000_000} // $1 per turn
+
+	g1, err := tr.NewTaskGuard("u1", "org/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := tr.NewTaskGuard("u2", "org/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if status := g1.AddTurn("claude-sonnet-4-5", turn); status != GuardOK {
+			t.Fatalf("g1 turn %d: status %v", i+1, status)
+		}
+	}
+	if limit, _ := tr.TaskCostLimit("u3", "org/c"); limit != 6 {
+		t.Errorf("TaskCostLimit() with $4 in flight = %v, want 6", limit)
+	}
+
+	// Together the running tasks reach the daily budget
+	for i := 0; i < 5; i++ {
+		if status := g2.AddTurn("claude-sonnet-4-5", turn); status != GuardOK {
+			t.Fatalf("g2 turn %d: status %v", i+1, status)
+		}
+	}
+	if status := g2.AddTurn("claude-sonnet-4-5", turn); status != GuardExceeded {
+		t.Errorf("g2 turn 6 with $10 in flight: status %v, want GuardExceeded", status)
+	}
+	var exhausted *ExhaustedError
+	if _, err := tr.NewTaskGuard("u3", "org/c"); !errors.As(err, &exhausted) || exhausted.Scope != "daily" {
+		t.Errorf("NewTaskGuard() with $10 in flight: %v, want the daily budget exhausted", err)
+	}
+
+	// Finished tasks count with their recorded cost instead
+	g1.Release()
+	g1.Release()
+	tr.Record("u1", []string{"org/a"}, 1)
+	g2.Release()
+	tr.Record("u2", []string{"org/b"}, 2)
+	if limit, capped := tr.TaskCostLimit("u3", "org/c"); limit != 7 || !capped {
+		t.Errorf("TaskCostLimit() after recording = %v, %v; want 7, true", limit, capped)
+	}
+}
+
+func TestTaskGuardLimits(t *testing.T) {
+	tr := NewTracker(Limits{TaskCost: 1, TaskTurns: 10, WarnRatio: 0.5, InputPricePerMTok: 0, OutputPricePerMTok: 1})
+	g, err := tr.NewTaskGuard("u1", "org/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Release()
+
+	turn := Usage{OutputTokens: 300_000} // $0.30 per turn at the default price
+	want := []GuardStatus{GuardOK, GuardWarn, GuardOK, GuardExceeded}
+	for i, w := range want {
+		if got := g.AddTurn("unknown-model", turn); got != w {
+			t.Errorf("turn %d: status %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestPriceOf(t *testing.T) {
+	l := Limits{
+		InputPricePerMTok:  3,
+		OutputPricePerMTok: 15,
+		ModelPrices: map[string]Price{
+			"opus":            {15, 75},
+			"claude-opus-4-5": {5, 25},
+			"haiku":           {1, 5},
+		},
+	}
+	tests := []struct {
+		model string
+		want  Price
+	}{
+		{"opus", Price{15, 75}},
+		{"claude-opus-4-1-20250805", Price{15, 75}},
+		{"claude-opus-4-5-20251101", Price{5, 25}},
+		{"claude-haiku-4-5", Price{1, 5}},
+		{"claude-sonnet-4-5", Price{3, 15}},
+		{"", Price{3, 15}},
+	}
+	for _, tt := range tests {
+		if got := l.PriceOf(tt.model); got != tt.want {
+			t.Errorf("PriceOf(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
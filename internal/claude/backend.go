@@ -0,0 +1,98 @@
+package claude
+
+import (
+	"context"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+)
+
+// Backend names of the implementations in this package.
+const (
+	BackendCLI    = "claude-cli"
+	BackendReplay = "replay"
+)
+
+// Name implements backend.Backend.
+func (r *Runner) Name() string { return BackendCLI }
+
+// Start implements backend.Backend by running the claude CLI in the background.
+func (r *Runner) Start(ctx context.Context, task backend.Task) (backend.Execution, error) {
+	return backend.Go(ctx, func(ctx context.Context, emit func(backend.Event)) (*backend.Result, error) {
+		result, err := r.Run(ctx, task.Prompt, task.Mode, runOptions(task), progressEmitter(emit))
+		return toBackendResult(result), err
+	}), nil
+}
+
+// Name implements backend.Backend.
+func (r *ReplayRunner) Name() string { return BackendReplay }
+
+// Start implements backend.Backend by replaying the recording in the background.
+func (r *ReplayRunner) Start(ctx context.Context, task backend.Task) (backend.Execution, error) {
+	return backend.Go(ctx, func(ctx context.Context, emit func(backend.Event)) (*backend.Result, error) {
+		result, err := r.Run(ctx, task.Prompt, task.Mode, runOptions(task), progressEmitter(emit))
+		return toBackendResult(result), err
+	}), nil
+}
+
+func runOptions(task backend.Task) RunOptions {
+	return RunOptions{
+		SessionID:     task.ResumeSessionID,
+		MaxTurns:      task.MaxTurns,
+		Model:         task.Model,
+		FallbackModel: task.FallbackModel,
+		Related:       task.Related,
+		Requester:     task.Requester,
+		Transcript:    task.Transcript,
+		Stderr:        task.Stderr,
+	}
+}
+
+// progressEmitter translates Parser callbacks into normalized events.
+func progressEmitter(emit func(backend.Event)) ProgressCallback {
+	return func(evt ProgressEvent) {
+		switch evt.Type {
+		case ProgressSession:
+			emit(backend.Event{Type: backend.EventSession, SessionID: evt.SessionID, Model: evt.Model})
+		case ProgressTurn:
+			var usage *backend.Usage
+			if evt.Usage != nil {
+				usage = &backend.Usage{
+					InputTokens:              evt.Usage.InputTokens,
+					OutputTokens:             evt.Usage.OutputTokens,
+					CacheCreationInputTokens: evt.Usage.CacheCreationInputTokens,
+					CacheReadInputTokens:     evt.Usage.CacheReadInputTokens,
+				}
+			}
+			emit(backend.Event{Type: backend.EventTurn, Usage: usage, Model: evt.Model})
+		case ProgressText:
+			emit(backend.Event{Type: backend.EventText, Text: evt.Text})
+		case ProgressToolUse:
+			emit(backend.Event{
+				Type:        backend.EventToolUse,
+				ToolName:    evt.ToolName,
+				ToolID:      evt.ToolID,
+				ToolInput:   evt.ToolInput,
+				ToolSummary: FormatToolSummary(evt.ToolName, evt.ToolInput),
+			})
+		case ProgressToolResult:
+			emit(backend.Event{Type: backend.EventToolResult, ToolID: evt.ToolID, IsError: evt.IsError})
+		case ProgressComplete:
+			emit(backend.Event{Type: backend.EventDone, Result: toBackendResult(evt.Result)})
+		}
+	}
+}
+
+func toBackendResult(r *Result) *backend.Result {
+	if r == nil {
+		return nil
+	}
+	return &backend.Result{
+		SessionID: r.SessionID,
+		Text:      r.Result,
+		IsError:   r.IsError,
+		Cost:      r.TotalCost,
+		Turns:     r.NumTurns,
+		Duration:  time.Duration(r.Duration * float64(time.Millisecond)),
+	}
+}
@@ -0,0 +1,46 @@
+package claude
+
+import (
+	"strings"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+)
+
+// failurePatterns map substrings of the CLI's stderr or error result (lower
+// case) to failure kinds. The first match wins.
+var failurePatterns = []struct {
+	kind     backend.FailureKind
+	patterns []string
+}{
+	{backend.FailureAuth, []string{"oauth token has expired", "invalid api key", "please run /login", "authentication_error", "not logged in", "api error: 401"}},
+	{backend.FailureRateLimited, []string{"rate_limit", "rate limit", "usage limit", "api error: 429"}},
+	{backend.FailureOverloaded, []string{"overloaded", "api error: 529", "api error: 503", "service unavailable"}},
+	{backend.FailureNetwork, []string{"econnreset", "etimedout", "econnrefused", "enotfound", "socket hang up", "fetch failed", "connection error", "network error"}},
+	{backend.FailureCancelled, []string{"request was aborted", "interrupted by user"}},
+}
+
+// classifyFailure decides why a run failed from its stderr and error result.
+func classifyFailure(stderr string, result *Result) backend.FailureKind {
+	text := stderr
+	if result != nil {
+		if result.Subtype == "error_max_turns" {
+			return backend.FailureMaxTurns
+		}
+		text += "\n" + result.Result
+	}
+	text = strings.ToLower(text)
+
+	for _, fp := range failurePatterns {
+		for _, p := range fp.patterns {
+			if strings.Contains(text, p) {
+				return fp.kind
+			}
+		}
+	}
+	return backend.FailureUnknown
+}
+
+// failed reports whether a result describes a failed run.
+func failed(result *Result) bool {
+	return result != nil && (result.IsError || strings.HasPrefix(result.Subtype, "error"))
+}
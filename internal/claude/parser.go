@@ -10,14 +10,16 @@ import (
 
 // Parser processes stream-json output from Claude CLI.
 type Parser struct {
-	logger   *slog.Logger
-	callback ProgressCallback
+	logger       *slog.Logger
+	callback     ProgressCallback
+	seenMessages map[string]bool // assistant message IDs already reported as turns
 }
 
 func NewParser(logger *slog.Logger, callback ProgressCallback) *Parser {
 	return &Parser{
-		logger:   logger,
-		callback: callback,
+		logger:       logger,
+		callback:     callback,
+		seenMessages: make(map[string]bool),
 	}
 }
 
@@ -49,6 +51,13 @@ func (p *Parser) Parse(r io.Reader) (*Result, error) {
 				continue
 			}
 			p.logger.Info("claude session", "session_id", evt.SessionID)
+			if evt.SessionID != "" {
+				p.callback(ProgressEvent{
+					Type:      ProgressSession,
+					SessionID: evt.SessionID,
+					Model:     evt.Model,
+				})
+			}
 
 		case "assistant":
 			var evt AssistantEvent
@@ -58,6 +67,13 @@ func (p *Parser) Parse(r io.Reader) (*Result, error) {
 			}
 			p.handleAssistant(evt)
 
+		case "user":
+			var evt UserEvent
+			if err := json.Unmarshal([]byte(line), &evt); err != nil {
+				continue
+			}
+			p.handleUser(evt)
+
 		case "result":
 			var evt Result
 			if err := json.Unmarshal([]byte(line), &evt); err != nil {
@@ -83,6 +99,20 @@ func (p *Parser) Parse(r io.Reader) (*Result, error) {
 }
 
 func (p *Parser) handleAssistant(evt AssistantEvent) {
+	// The CLI may emit one assistant event per content block of the same message,
+	// so report a turn only the first time a message ID is seen.
+	if id := evt.Message.ID; id == "" || !p.seenMessages[id] {
+		if id != "" {
+			p.seenMessages[id] = true
+		}
+		usage := evt.Message.Usage
+		p.callback(ProgressEvent{
+			Type:  ProgressTurn,
+			Usage: &usage,
+			Model: evt.Message.Model,
+		})
+	}
+
 	for _, block := range evt.Message.Content {
 		switch block.Type {
 		case "text":
@@ -107,6 +137,19 @@ func (p *Parser) handleAssistant(evt AssistantEvent) {
 	}
 }
 
+func (p *Parser) handleUser(evt UserEvent) {
+	for _, block := range evt.Message.Content {
+		if block.Type != "tool_result" {
+			continue
+		}
+		p.callback(ProgressEvent{
+			Type:    ProgressToolResult,
+			ToolID:  block.ToolUseID,
+			IsError: block.IsError,
+		})
+	}
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -0,0 +1,131 @@
+package claude
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/backend"
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// defaultReplayInterval paces lines of recordings without timing information.
+const defaultReplayInterval = 200 * time.Millisecond
+
+// ReplayConfig configures a ReplayRunner.
+type ReplayConfig struct {
+	// Open opens the recorded stream-json output; it is called once per run.
+	Open func() (io.ReadCloser, error)
+
+	// Offsets are the arrival times of the recorded lines. Lines without an
+	// offset follow the previous one after defaultReplayInterval.
+	Offsets []time.Duration
+
+	// Speed scales the recorded timing: 1 plays in real time, 10 ten times
+	// faster. 0 feeds all lines without waiting.
+	Speed float64
+
+	WorkDir string
+}
+
+// ReplayRunner feeds a recorded stream-json output through Parser instead of
+// running claude, so progress rendering and summaries can be exercised offline.
+// The prompt is ignored; every run replays the same recording.
+type ReplayRunner struct {
+	cfg    ReplayConfig
+	logger *slog.Logger
+}
+
+func NewReplayRunner(cfg ReplayConfig, logger *slog.Logger) *ReplayRunner {
+	return &ReplayRunner{cfg: cfg, logger: logger}
+}
+
+// Run replays the recording, honouring cancellation of ctx between lines.
+func (r *ReplayRunner) Run(ctx context.Context, prompt string, mode domain.AgentMode, opts RunOptions, callback ProgressCallback) (*Result, error) {
+	stream, err := r.cfg.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer stream.Close()
+
+	r.logger.Info("replaying recorded run", "workdir", r.cfg.WorkDir, "speed", r.cfg.Speed, "timed_lines", len(r.cfg.Offsets))
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(r.feed(ctx, stream, pw))
+	}()
+
+	var out io.Reader = pr
+	if opts.Transcript != nil {
+		out = io.TeeReader(pr, opts.Transcript)
+	}
+
+	parser := NewParser(r.logger, callback)
+	result, parseErr := parser.Parse(out)
+	pr.Close()
+
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if parseErr != nil {
+		return result, fmt.Errorf("parse error: %w", parseErr)
+	}
+	if failed(result) {
+		return result, &backend.Error{
+			Kind: classifyFailure("", result),
+			Err:  fmt.Errorf("recorded run reported an error (%s): %s", result.Subtype, result.Result),
+		}
+	}
+	return result, nil
+}
+
+// feed copies the recording to w line by line with the recorded pacing.
+func (r *ReplayRunner) feed(ctx context.Context, stream io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(stream)
+	var prev time.Duration
+	for i := 0; ; i++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			at := prev + defaultReplayInterval
+			if i < len(r.cfg.Offsets) {
+				at = r.cfg.Offsets[i]
+			}
+			if err := r.wait(ctx, at-prev); err != nil {
+				return err
+			}
+			prev = at
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *ReplayRunner) wait(ctx context.Context, d time.Duration) error {
+	if r.cfg.Speed <= 0 || d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(time.Duration(float64(d) / r.cfg.Speed))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WorkDir returns the directory the replayed run pretends to work in.
+func (r *ReplayRunner) WorkDir() string {
+	return r.cfg.WorkDir
+}
@@ -4,71 +4,106 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/toshin/slack-claude-agent/internal/backend"
 	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/gitenv"
+	"github.com/toshin/slack-claude-agent/internal/githost"
 )
 
 type Runner struct {
 	claudePath      string
 	workspacePath   string
+	workDir         string
 	githubOwner     string
 	githubRepo      string
 	defaultBranch   string
+	target          domain.Target
+	gitHost         string
+	env             []string
 	authorName      string
 	authorEmail     string
 	coAuthorName    string
 	coAuthorEmail   string
-	semaphore       chan struct{}
+	attribution     domain.AttributionMode
 	logger          *slog.Logger
 }
 
 type Config struct {
 	ClaudePath    string
 	WorkspacePath string
+	WorkDir       string // repository checkout; default <WorkspacePath>/<GitHubOwner>/<GitHubRepo>
 	GitHubOwner   string
 	GitHubRepo    string
 	DefaultBranch string
+	Target        domain.Target    // monorepo sub-project, if any
+	GitHost       string           // githost name, for the pull request commands in the prompt; "" means GitHub
+	Env           []string         // extra environment of claude and the commands it runs (e.g. GH_HOST)
+	GitConfig     []gitenv.Setting // git settings of the commands (e.g. commit signing)
+	HooksPath     string           // hooks adding the Co-Authored-By trailer (gitenv.InstallHooks); "" for none
 	AuthorName    string
 	AuthorEmail   string
 	CoAuthorName  string
 	CoAuthorEmail string
-	MaxConcurrent int
+	Attribution   domain.AttributionMode // who authors commits when the requester is known; default bot
 }
 
 func NewRunner(cfg Config, logger *slog.Logger) *Runner {
+	settings := append([]gitenv.Setting(nil), cfg.GitConfig...)
+	if cfg.HooksPath != "" {
+		settings = append(settings, gitenv.Setting{Key: "core.hooksPath", Value: cfg.HooksPath})
+	}
 	return &Runner{
 		claudePath:    cfg.ClaudePath,
 		workspacePath: cfg.WorkspacePath,
+		workDir:       cfg.WorkDir,
 		githubOwner:   cfg.GitHubOwner,
 		githubRepo:    cfg.GitHubRepo,
 		defaultBranch: cfg.DefaultBranch,
+		target:        cfg.Target,
+		gitHost:       cfg.GitHost,
+		env:           append(cfg.Env, gitenv.Env(settings)...),
 		authorName:    cfg.AuthorName,
 		authorEmail:   cfg.AuthorEmail,
 		coAuthorName:  cfg.CoAuthorName,
 		coAuthorEmail: cfg.CoAuthorEmail,
-		semaphore:     make(chan struct{}, cfg.MaxConcurrent),
+		attribution:   cfg.Attribution,
 		logger:        logger,
 	}
 }
 
-// Run executes claude CLI with the given prompt.
-// workDir is where the repository is located.
-func (r *Runner) Run(ctx context.Context, prompt string, mode domain.AgentMode, sessionID string, callback ProgressCallback) (*Result, error) {
-	// Acquire semaphore
-	select {
-	case r.semaphore <- struct{}{}:
-		defer func() { <-r.semaphore }()
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	}
+// RunOptions holds per-run options for the claude CLI.
+type RunOptions struct {
+	SessionID     string // resume this Claude session if set
+	MaxTurns      int    // --max-turns (0 = CLI default)
+	Model         string // --model (empty = CLI default)
+	FallbackModel string // --fallback-model, used when the model is overloaded
+
+	// Related repositories are passed with --add-dir and described in the prompt.
+	Related []backend.RelatedRepo
 
+	// Requester is the git identity of the user who asked for the task; nil
+	// attributes commits to the bot and the configured co-author.
+	Requester *domain.GitIdentity
+
+	// Transcript and Stderr, if set, receive a copy of the raw stream-json
+	// output and of stderr.
+	Transcript io.Writer
+	Stderr     io.Writer
+}
+
+// Run executes claude CLI with the given prompt.
+// Concurrency is limited by the caller (see scheduler.Scheduler).
+func (r *Runner) Run(ctx context.Context, prompt string, mode domain.AgentMode, opts RunOptions, callback ProgressCallback) (*Result, error) {
 	// Build full prompt with instructions
-	fullPrompt := r.buildPrompt(prompt, mode)
+	fullPrompt := r.buildPrompt(prompt, mode, opts.Related)
 
 	args := []string{
 		"--print",
@@ -78,20 +113,40 @@ func (r *Runner) Run(ctx context.Context, prompt string, mode domain.AgentMode,
 	}
 
 	// Resume session if sessionID is provided
-	if sessionID != "" {
-		args = append(args, "--resume", sessionID)
+	if opts.SessionID != "" {
+		args = append(args, "--resume", opts.SessionID)
+	}
+
+	if opts.MaxTurns > 0 {
+		args = append(args, "--max-turns", strconv.Itoa(opts.MaxTurns))
+	}
+
+	if opts.Model != "" {
+		args = append(args, "--model", opts.Model)
+	}
+	// The CLI rejects a fallback identical to the main model
+	if opts.FallbackModel != "" && opts.FallbackModel != opts.Model {
+		args = append(args, "--fallback-model", opts.FallbackModel)
+	}
+
+	for _, rel := range opts.Related {
+		args = append(args, "--add-dir", rel.Dir)
 	}
 
 	args = append(args, fullPrompt)
 
-	workDir := filepath.Join(r.workspacePath, r.githubRepo)
+	workDir := r.WorkDir()
 	r.logger.Info("running claude", "workdir", workDir, "args_count", len(args))
 
 	cmd := exec.CommandContext(ctx, r.claudePath, args...)
 	cmd.Dir = workDir
+	cmd.Env = append(append(os.Environ(), r.env...), r.commitEnv(opts.Requester)...)
 
 	var stderrBuf bytes.Buffer
 	cmd.Stderr = &stderrBuf
+	if opts.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderrBuf, opts.Stderr)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -102,8 +157,13 @@ func (r *Runner) Run(ctx context.Context, prompt string, mode domain.AgentMode,
 		return nil, fmt.Errorf("start claude: %w", err)
 	}
 
+	var out io.Reader = stdout
+	if opts.Transcript != nil {
+		out = io.TeeReader(stdout, opts.Transcript)
+	}
+
 	parser := NewParser(r.logger, callback)
-	result, parseErr := parser.Parse(stdout)
+	result, parseErr := parser.Parse(out)
 
 	waitErr := cmd.Wait()
 
@@ -119,21 +179,178 @@ func (r *Runner) Run(ctx context.Context, prompt string, mode domain.AgentMode,
 		if ctx.Err() != nil {
 			return result, ctx.Err()
 		}
-		return result, fmt.Errorf("claude exited with error: %w (stderr: %s)", waitErr, stderrStr)
+		return result, &backend.Error{
+			Kind: classifyFailure(stderrStr, result),
+			Err:  fmt.Errorf("claude exited with error: %w (stderr: %s)", waitErr, stderrStr),
+		}
+	}
+	if failed(result) {
+		return result, &backend.Error{
+			Kind: classifyFailure(stderrStr, result),
+			Err:  fmt.Errorf("claude reported an error (%s): %s", result.Subtype, result.Result),
+		}
 	}
 
 	return result, nil
 }
 
-func (r *Runner) buildPrompt(instruction string, mode domain.AgentMode) string {
+// WorkDir returns the directory of the repository checkout claude runs in.
+func (r *Runner) WorkDir() string {
+	if r.workDir != "" {
+		return r.workDir
+	}
+	return filepath.Join(r.workspacePath, r.githubOwner, r.githubRepo)
+}
+
+// commitEnv sets the author, committer and co-author of the run's commits.
+// The bot (AUTHOR_NAME) always commits; who authors depends on the
+// attribution mode. Without a known requester the bot authors, Co-Authored-By
+// CO_AUTHOR_NAME as before.
+func (r *Runner) commitEnv(requester *domain.GitIdentity) []string {
+	bot := domain.GitIdentity{Name: r.authorName, Email: r.authorEmail}
+	author, coAuthor := bot, domain.GitIdentity{Name: r.coAuthorName, Email: r.coAuthorEmail}
+	if requester != nil {
+		coAuthor = *requester
+		if r.attribution == domain.AttributionUser {
+			author, coAuthor = *requester, bot
+		}
+	}
+	return []string{
+		"GIT_AUTHOR_NAME=" + author.Name,
+		"GIT_AUTHOR_EMAIL=" + author.Email,
+		"GIT_COMMITTER_NAME=" + bot.Name,
+		"GIT_COMMITTER_EMAIL=" + bot.Email,
+		"CO_AUTHORED_BY=Co-Authored-By: " + coAuthor.String(),
+	}
+}
+
+// targetRules describes the monorepo sub-project the task is scoped to.
+func (r *Runner) targetRules() string {
+	if r.target.Name == "" {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nTarget: %s (the %s directory of the repository; you start in it)\n", r.target.Name, r.target.Dir)
+	b.WriteString("- Keep changes within this directory unless the task requires otherwise\n")
+	if r.target.TestCommand != "" {
+		fmt.Fprintf(&b, "- Run the tests with: %s\n", r.target.TestCommand)
+	}
+	if r.target.Prompt != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.target.Prompt)
+	}
+	return b.String()
+}
+
+// relatedRules describes the other repositories of a multi-repository task
+// and how their pull requests are linked.
+func relatedRules(primary, primaryHost string, related []backend.RelatedRepo) string {
+	if len(related) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nThis task spans several repositories. You start in %s; the others are:\n", primary)
+	for _, rel := range related {
+		cmds := prCommandsFor(rel.GitHost)
+		fmt.Fprintf(&b, "- %s at %s (default branch: %s; create its %s with '%s')\n",
+			rel.Key, rel.Dir, rel.DefaultBranch, cmds.Noun, cmds.createIn(rel.Key))
+	}
+	b.WriteString(`For every repository you change:
+- Follow the rules above in it as well (feature branch, never push to its default branch)
+- Use the same feature branch name in every repository
+- Run git and the commands above from inside its directory
+After all of them exist, add a "Related pull requests" section listing the URLs of the others to each description.
+`)
+	for _, cmds := range distinctCommands(append([]string{primaryHost}, hostsOf(related)...)) {
+		if cmds.Edit != "" {
+			fmt.Fprintf(&b, "Edit a %s description with '%s'.\n", cmds.Noun, cmds.Edit)
+		}
+	}
+	return b.String()
+}
+
+func hostsOf(related []backend.RelatedRepo) []string {
+	hosts := make([]string, len(related))
+	for i, rel := range related {
+		hosts[i] = rel.GitHost
+	}
+	return hosts
+}
+
+// distinctCommands returns the pull request commands of the hosts, once each.
+func distinctCommands(hosts []string) []prCommands {
+	var result []prCommands
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		cmds := prCommandsFor(host)
+		if !seen[cmds.Create] {
+			seen[cmds.Create] = true
+			result = append(result, cmds)
+		}
+	}
+	return result
+}
+
+// prCommands tells Claude how to work with the pull requests of a git host.
+type prCommands struct {
+	Noun     string // "pull request" or "merge request"
+	Create   string
+	RepoFlag bool   // Create accepts --repo <owner/name>
+	Edit     string // updates the description; "" if there is no command
+	Comment  string // leaves a review comment
+}
+
+func prCommandsFor(host string) prCommands {
+	switch host {
+	case githost.HostGitLab:
+		return prCommands{
+			Noun:     "merge request",
+			Create:   "glab mr create --fill --yes",
+			RepoFlag: true,
+			Edit:     "glab mr update <number> --description ...",
+			Comment:  "glab mr note <number> --message ...",
+		}
+	case githost.HostGitea:
+		return prCommands{
+			Noun:     "pull request",
+			Create:   "tea pulls create",
+			RepoFlag: true,
+			Edit:     "tea pulls edit <number> --description ...",
+			Comment:  "tea comment <number> ...",
+		}
+	case githost.HostLocal:
+		return prCommands{
+			Noun:    "pull request",
+			Create:  "git push -u origin <branch>",
+			Comment: "your reply (there is no review tool)",
+		}
+	default:
+		return prCommands{
+			Noun:     "pull request",
+			Create:   "gh pr create",
+			RepoFlag: true,
+			Edit:     "gh pr edit <url> --body-file ...",
+			Comment:  "gh pr review <number> --comment --body ...",
+		}
+	}
+}
+
+// createIn returns the create command for the repository key.
+func (c prCommands) createIn(key string) string {
+	if !c.RepoFlag {
+		return c.Create
+	}
+	return c.Create + " --repo " + key
+}
+
+func (r *Runner) buildPrompt(instruction string, mode domain.AgentMode, related []backend.RelatedRepo) string {
+	cmds := prCommandsFor(r.gitHost)
 	commonRules := fmt.Sprintf(`
 Repository: %s/%s
 Default branch: %s
 
-When creating commits, use this format:
-git commit -m "Your commit message
-
-Co-Authored-By: %s <%s>"
+When creating commits, use: git commit -m "Your commit message"
+The environment sets the author and committer, and a commit-msg hook adds the Co-Authored-By trailer.
+Never change the commit author or committer (no --author, no git config user.*), and never skip hooks (no --no-verify).
 
 CRITICAL RULES (MUST FOLLOW):
 - NEVER EVER merge any branch into main/master/develop
@@ -142,17 +359,19 @@ CRITICAL RULES (MUST FOLLOW):
 - NEVER run 'git push origin main' or 'git push origin master'
 - ALWAYS create a feature branch (e.g., feature/your-feature-name)
 - ALWAYS push to the feature branch only
-- ALWAYS create a pull request using 'gh pr create'
+- ALWAYS create a %s using '%s'
 - If you accidentally try to push to main, STOP immediately and create a feature branch instead
 
 These rules are NON-NEGOTIABLE. Violating them will result in permanent data loss.
-`,
+%s%s`,
 		r.githubOwner,
 		r.githubRepo,
 		r.defaultBranch,
-		r.coAuthorName,
-		r.coAuthorEmail,
 		r.defaultBranch,
+		cmds.Noun,
+		cmds.Create,
+		r.targetRules(),
+		relatedRules(r.githubOwner+"/"+r.githubRepo, r.gitHost, related),
 	)
 
 	switch mode {
@@ -166,7 +385,7 @@ Instructions:
 1. Review the code changes carefully
 2. Check for bugs, security issues, performance problems, and best practices
 3. Provide constructive feedback with specific suggestions
-4. If changes are needed, create a review comment on the PR
+4. If changes are needed, create a review comment on the %s ('%s')
 5. DO NOT make code changes directly - only provide review feedback
 
 Focus on:
@@ -178,6 +397,8 @@ Focus on:
 `,
 			instruction,
 			commonRules,
+			cmds.Noun,
+			cmds.Comment,
 		)
 
 	case domain.ModeImplementation:
@@ -192,22 +413,17 @@ Instructions:
 1. Implement the requested changes
 2. Create a new branch with a descriptive name
 3. Commit your changes with a clear commit message
-4. Create a pull request using 'gh pr create'
+4. Create a %s using '%s'
 5. Write clean, maintainable code following best practices
 `,
 			instruction,
 			commonRules,
+			cmds.Noun,
+			cmds.Create,
 		)
 	}
 }
 
-// RunWithTimeout wraps Run with a timeout.
-func (r *Runner) RunWithTimeout(ctx context.Context, prompt string, mode domain.AgentMode, sessionID string, timeout time.Duration, callback ProgressCallback) (*Result, error) {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	return r.Run(ctx, prompt, mode, sessionID, callback)
-}
-
 // FormatToolSummary creates a human-readable summary of a tool invocation.
 func FormatToolSummary(name string, input map[string]interface{}) string {
 	switch name {
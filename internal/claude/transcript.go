@@ -0,0 +1,146 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// TranscriptKind is the kind of a transcript entry.
+type TranscriptKind string
+
+const (
+	TranscriptSystem     TranscriptKind = "system"
+	TranscriptText       TranscriptKind = "text"
+	TranscriptToolUse    TranscriptKind = "tool_use"
+	TranscriptToolResult TranscriptKind = "tool_result"
+	TranscriptResult     TranscriptKind = "result"
+)
+
+// TranscriptEntry is one step of a recorded run, in display order.
+type TranscriptEntry struct {
+	Kind     TranscriptKind
+	Text     string // assistant text, tool result content or final result
+	ToolName string
+	ToolID   string
+	Input    string // pretty-printed tool input JSON
+	IsError  bool
+	Result   *Result // set for TranscriptResult
+}
+
+// ReadTranscript decodes raw stream-json output into display entries.
+// Unknown and malformed lines are skipped.
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+
+	var entries []TranscriptEntry
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var env StreamEvent
+		if err := json.Unmarshal(line, &env); err != nil {
+			continue
+		}
+
+		switch env.Type {
+		case "system":
+			var evt SystemEvent
+			if err := json.Unmarshal(line, &evt); err == nil && evt.SessionID != "" {
+				entries = append(entries, TranscriptEntry{Kind: TranscriptSystem, Text: "session " + evt.SessionID})
+			}
+
+		case "assistant":
+			var evt AssistantEvent
+			if err := json.Unmarshal(line, &evt); err != nil {
+				continue
+			}
+			for _, block := range evt.Message.Content {
+				switch block.Type {
+				case "text":
+					if block.Text != "" {
+						entries = append(entries, TranscriptEntry{Kind: TranscriptText, Text: block.Text})
+					}
+				case "tool_use":
+					entries = append(entries, TranscriptEntry{
+						Kind:     TranscriptToolUse,
+						ToolName: block.Name,
+						ToolID:   block.ID,
+						Input:    prettyJSON(block.Input),
+					})
+				}
+			}
+
+		case "user":
+			var evt UserEvent
+			if err := json.Unmarshal(line, &evt); err != nil {
+				continue
+			}
+			for _, block := range evt.Message.Content {
+				if block.Type != "tool_result" {
+					continue
+				}
+				entries = append(entries, TranscriptEntry{
+					Kind:    TranscriptToolResult,
+					ToolID:  block.ToolUseID,
+					Text:    toolResultText(block.Content),
+					IsError: block.IsError,
+				})
+			}
+
+		case "result":
+			var evt Result
+			if err := json.Unmarshal(line, &evt); err != nil {
+				continue
+			}
+			entries = append(entries, TranscriptEntry{
+				Kind:    TranscriptResult,
+				Text:    evt.Result,
+				IsError: evt.IsError,
+				Result:  &evt,
+			})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func prettyJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// toolResultText flattens tool_result content, which is either a string or a
+// list of content blocks.
+func toolResultText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return string(raw)
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		} else {
+			parts = append(parts, "["+b.Type+"]")
+		}
+	}
+	return strings.Join(parts, "\n")
+}
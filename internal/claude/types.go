@@ -14,6 +14,7 @@ type SystemEvent struct {
 	Type      string `json:"type"`    // "system"
 	Subtype   string `json:"subtype"` // "init"
 	SessionID string `json:"session_id"`
+	Model     string `json:"model"`
 }
 
 // AssistantEvent contains the assistant's response message.
@@ -25,16 +26,38 @@ type AssistantEvent struct {
 
 // AssistantMessage is the message payload in an assistant event.
 type AssistantMessage struct {
+	ID      string         `json:"id"`
+	Model   string         `json:"model"`
 	Content []ContentBlock `json:"content"`
+	Usage   Usage          `json:"usage"`
 }
 
-// ContentBlock represents a content block (text or tool_use).
+// Usage is the token usage of a single assistant message.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// ContentBlock represents a content block (text, tool_use or tool_result).
 type ContentBlock struct {
-	Type  string          `json:"type"` // "text", "tool_use"
-	Text  string          `json:"text,omitempty"`
-	ID    string          `json:"id,omitempty"`
-	Name  string          `json:"name,omitempty"`
-	Input json.RawMessage `json:"input,omitempty"`
+	Type      string          `json:"type"` // "text", "tool_use", "tool_result"
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result only
+	IsError   bool            `json:"is_error,omitempty"`    // tool_result only
+	Content   json.RawMessage `json:"content,omitempty"`     // tool_result only: string or blocks
+}
+
+// UserEvent carries tool results sent back to the model.
+type UserEvent struct {
+	Type    string `json:"type"` // "user"
+	Message struct {
+		Content []ContentBlock `json:"content"`
+	} `json:"message"`
 }
 
 // Result is the final result event.
@@ -61,6 +84,10 @@ type ProgressEvent struct {
 	ToolInput map[string]interface{} // parsed tool input for context
 	IsFinal   bool
 	Result    *Result
+	Usage     *Usage // set for ProgressTurn
+	SessionID string // set for ProgressSession
+	Model     string // set for ProgressSession and ProgressTurn, if reported
+	IsError   bool   // set for ProgressToolResult
 }
 
 type ProgressType int
@@ -71,4 +98,6 @@ const (
 	ProgressToolResult
 	ProgressComplete
 	ProgressError
+	ProgressTurn    // a new assistant message (turn) started; carries its usage
+	ProgressSession // the Claude session was initialized; carries its ID
 )
@@ -2,14 +2,19 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/toshin/slack-claude-agent/internal/budget"
 	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+	"github.com/toshin/slack-claude-agent/internal/scheduler"
+	"github.com/toshin/slack-claude-agent/internal/signing"
 )
 
 type Config struct {
@@ -18,7 +23,12 @@ type Config struct {
 	SlackAppToken string
 
 	// Workspace
-	WorkspacePath string // parent directory containing the repository
+	WorkspacePath    string            // parent directory containing the repositories
+	WorkspacePrepare bool              // fetch and check out the latest default branch before each run
+	WorkspaceDirty   string            // uncommitted changes left in a checkout: "stash" or "refuse"
+	WorkspaceClone   bool              // clone repositories missing from WorkspacePath
+	WorkspaceMigrate bool              // move flat <WorkspacePath>/<name> checkouts to <owner>/<name>
+	WorkspacePaths   map[string]string // explicit checkout path per repository key
 
 	// GitHub (legacy single repository support)
 	GitHubOwner   string
@@ -29,15 +39,83 @@ type Config struct {
 	Repositories      []*domain.Repository
 	DefaultRepository *domain.Repository
 
-	// Commit Author
+	// Commit Author (the bot) and the co-author used when the requester's
+	// identity is unknown
 	AuthorName    string
 	AuthorEmail   string
 	CoAuthorName  string
 	CoAuthorEmail string
 
+	// Per-user commit attribution
+	CommitAttribution       domain.AttributionMode // "bot" (default) or "user" authors the commits
+	CommitIdentitiesPath    string                 // JSON file mapping Slack user IDs to git identities
+	CommitIdentityFromSlack bool                   // fall back to the Slack profile's name and email
+	CommitHooksPath         string                 // where the hooks adding Co-Authored-By trailers are installed
+
+	// Commit signing, overridable per repository
+	Signing           signing.Config
+	RepoSigningFormat map[string]string // key: owner/repo
+	RepoSigningKey    map[string]string // key: owner/repo
+
 	// Claude
-	ClaudePath    string // path to claude CLI binary
-	MaxConcurrent int    // max concurrent claude runs
+	ClaudePath string // path to claude CLI binary
+
+	// Models (empty = backend default)
+	Models domain.ModelPolicy
+
+	// Agent backend: "claude-cli" (default) or "replay", overridable per repository
+	Backend      string
+	RepoBackends map[string]string // key: owner/repo
+
+	// Git hosting service of the pull requests: "github" (default), "gitlab",
+	// "gitea" or "local", overridable per repository
+	GitHost          string
+	RepoGitHosts     map[string]string // key: owner/repo
+	GitHostURL       string            // base URL of the GitLab or Gitea server
+	RepoGitHostURLs  map[string]string // key: owner/repo; overrides GitHostURL
+	GitLabToken      string
+	GiteaToken       string
+	GitHostLocalPath string // directory of the bare repositories of the "local" host
+
+	// Recorded run played back by the "replay" backend (staging, demos)
+	ReplayPath  string  // run directory or stream-json file
+	ReplaySpeed float64 // 1 = recorded timing, 10 = ten times faster, 0 = no delays
+
+	// Scheduling of concurrent claude runs
+	Scheduler  scheduler.Limits
+	Priorities map[domain.AgentMode]int // higher runs first
+
+	// Slack user IDs allowed to use admin commands (status, /claude-admin)
+	AdminUsers []string
+
+	// Budgets (0 = unlimited)
+	Budget budget.Limits
+
+	// Task timeouts
+	Timeouts domain.TimeoutPolicy
+
+	// Retries of transient failures (rate limit, overload, network)
+	Retries domain.RetryPolicy
+
+	// Usage accounting
+	UsageLedgerPath string // append-only JSON Lines file of all runs
+
+	// HTTP (health, readiness and metrics); empty disables the server
+	HTTPAddr string
+
+	// Run recordings and web UI (served on HTTPAddr; empty token disables the UI)
+	RunsPath      string        // one directory per run with transcript, stderr, diff and metadata
+	RunsMaxBytes  int64         // cap on each recorded output (uncompressed)
+	RunsRetention time.Duration // recorded runs older than this are deleted
+	WebUIToken    string        // shared token required to open the UI
+	WebUIBaseURL  string        // public URL of the HTTP server, used for links in Slack
+
+	// Tracing exporter: "otlp", "stdout" or empty to disable
+	TracingExporter string
+
+	// Shutdown
+	ShutdownGracePeriod  time.Duration // how long to wait for running tasks on SIGTERM
+	InterruptedTasksPath string        // where tasks interrupted by shutdown are saved
 }
 
 func Load() (*Config, error) {
@@ -53,13 +131,158 @@ func Load() (*Config, error) {
 		CoAuthorName:  getEnvDefault("CO_AUTHOR_NAME", "Claude"),
 		CoAuthorEmail: getEnvDefault("CO_AUTHOR_EMAIL", "noreply+claude@anthropic.com"),
 		ClaudePath:    getEnvDefault("CLAUDE_PATH", "claude"),
-		MaxConcurrent: getEnvIntDefault("MAX_CONCURRENT", 5),
+		Scheduler: scheduler.Limits{
+			Global:  getEnvIntDefault("MAX_CONCURRENT", 5),
+			PerRepo: getEnvIntDefault("MAX_CONCURRENT_PER_REPO", 0),
+			PerUser: getEnvIntDefault("MAX_CONCURRENT_PER_USER", 0),
+		},
+		Priorities: map[domain.AgentMode]int{
+			domain.ModeImplementation: getEnvIntDefault("PRIORITY_IMPLEMENTATION", 0),
+			domain.ModeReview:         getEnvIntDefault("PRIORITY_REVIEW", 10),
+		},
+		Budget: budget.Limits{
+			TaskCost:           getEnvFloatDefault("BUDGET_TASK_USD", 0),
+			TaskTurns:          getEnvIntDefault("BUDGET_TASK_MAX_TURNS", 0),
+			UserDailyCost:      getEnvFloatDefault("BUDGET_USER_DAILY_USD", 0),
+			RepoDailyCost:      getEnvFloatDefault("BUDGET_REPO_DAILY_USD", 0),
+			DailyCost:          getEnvFloatDefault("BUDGET_DAILY_USD", 0),
+			WarnRatio:          getEnvFloatDefault("BUDGET_WARN_RATIO", 0.8),
+			InputPricePerMTok:  getEnvFloatDefault("BUDGET_INPUT_PRICE_PER_MTOK", 3),
+			OutputPricePerMTok: getEnvFloatDefault("BUDGET_OUTPUT_PRICE_PER_MTOK", 15),
+		},
+		UsageLedgerPath:      getEnvDefault("USAGE_LEDGER_PATH", "data/usage.jsonl"),
+		HTTPAddr:             os.Getenv("HTTP_ADDR"),
+		RunsPath:             getEnvDefault("RUNS_PATH", "data/runs"),
+		RunsMaxBytes:         int64(getEnvIntDefault("RUNS_MAX_BYTES", 50*1024*1024)),
+		RunsRetention:        getEnvDurationDefault("RUNS_RETENTION", 30*24*time.Hour),
+		WebUIToken:           os.Getenv("WEBUI_TOKEN"),
+		WebUIBaseURL:         os.Getenv("WEBUI_BASE_URL"),
+		TracingExporter:      os.Getenv("TRACING_EXPORTER"),
+		WorkspacePrepare:     getEnvDefault("WORKSPACE_PREPARE", "true") == "true",
+		WorkspaceDirty:       getEnvDefault("WORKSPACE_DIRTY", "stash"),
+		WorkspaceClone:       getEnvDefault("WORKSPACE_CLONE", "true") == "true",
+		WorkspaceMigrate:     getEnvDefault("WORKSPACE_MIGRATE", "false") == "true",
+		Backend:              getEnvDefault("AGENT_BACKEND", "claude-cli"),
+		GitHost:              getEnvDefault("GIT_HOST", "github"),
+		GitHostURL:           os.Getenv("GIT_HOST_URL"),
+		GitLabToken:          os.Getenv("GITLAB_TOKEN"),
+		GiteaToken:           os.Getenv("GITEA_TOKEN"),
+		GitHostLocalPath:     os.Getenv("GIT_HOST_LOCAL_PATH"),
+		ReplayPath:           os.Getenv("REPLAY_PATH"),
+		ReplaySpeed:          getEnvFloatDefault("REPLAY_SPEED", 1),
+		ShutdownGracePeriod:  getEnvDurationDefault("SHUTDOWN_GRACE_PERIOD", 5*time.Minute),
+		InterruptedTasksPath: getEnvDefault("INTERRUPTED_TASKS_PATH", "data/interrupted.json"),
+		Models: domain.ModelPolicy{
+			Default:  os.Getenv("MODEL"),
+			Fallback: os.Getenv("MODEL_FALLBACK"),
+			PerMode: map[domain.AgentMode]string{
+				domain.ModeImplementation: os.Getenv("MODEL_IMPLEMENTATION"),
+				domain.ModeReview:         os.Getenv("MODEL_REVIEW"),
+			},
+			Allowed: splitList(os.Getenv("MODEL_ALLOWED")),
+		},
+		Retries: domain.RetryPolicy{
+			Max:        getEnvIntDefault("RETRY_MAX", 3),
+			Backoff:    getEnvDurationDefault("RETRY_BACKOFF", 30*time.Second),
+			MaxBackoff: getEnvDurationDefault("RETRY_BACKOFF_MAX", 5*time.Minute),
+		},
+		Timeouts: domain.TimeoutPolicy{
+			Default: getEnvDurationDefault("TASK_TIMEOUT", 30*time.Minute),
+			Min:     getEnvDurationDefault("TASK_TIMEOUT_MIN", time.Minute),
+			Max:     getEnvDurationDefault("TASK_TIMEOUT_MAX", 2*time.Hour),
+			Warning: getEnvDurationDefault("TASK_TIMEOUT_WARNING", 5*time.Minute),
+			PerMode: map[domain.AgentMode]time.Duration{
+				domain.ModeImplementation: getEnvDurationDefault("TASK_TIMEOUT_IMPLEMENTATION", 0),
+				domain.ModeReview:         getEnvDurationDefault("TASK_TIMEOUT_REVIEW", 0),
+			},
+		},
+	}
+
+	cfg.AdminUsers = splitList(os.Getenv("ADMIN_USERS"))
+
+	cfg.CommitAttribution = domain.AttributionMode(getEnvDefault("COMMIT_ATTRIBUTION", string(domain.AttributionBot)))
+	cfg.CommitIdentitiesPath = os.Getenv("COMMIT_IDENTITIES_PATH")
+	cfg.CommitIdentityFromSlack = getEnvDefault("COMMIT_IDENTITY_SLACK_PROFILE", "false") == "true"
+	cfg.CommitHooksPath = getEnvDefault("COMMIT_HOOKS_PATH", "data/git-hooks")
+
+	cfg.Signing = signing.Config{
+		Format:  signing.Format(os.Getenv("COMMIT_SIGNING_FORMAT")),
+		Key:     os.Getenv("COMMIT_SIGNING_KEY"),
+		GPGHome: os.Getenv("COMMIT_SIGNING_GPG_HOME"),
+	}
+
+	// Parsed before the repositories so auto-detection only fills in the rest
+	repoGitHosts, err := parseRepoMap(os.Getenv("GIT_HOST_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GIT_HOST_REPOS: %w", err)
+	}
+	cfg.RepoGitHosts = repoGitHosts
+
+	repoGitHostURLs, err := parseRepoMap(os.Getenv("GIT_HOST_URL_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GIT_HOST_URL_REPOS: %w", err)
 	}
+	cfg.RepoGitHostURLs = repoGitHostURLs
 
 	if err := cfg.loadRepositories(); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.loadTargets(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := parseRepoMap(os.Getenv("BUDGET_REPO_OVERRIDES"), parseFloat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BUDGET_REPO_OVERRIDES: %w", err)
+	}
+	cfg.Budget.RepoOverrides = overrides
+
+	cfg.Budget.ModelPrices = budget.DefaultModelPrices
+	if v := os.Getenv("BUDGET_MODEL_PRICES"); v != "" {
+		prices, err := parseRepoMap(v, parsePrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse BUDGET_MODEL_PRICES: %w", err)
+		}
+		cfg.Budget.ModelPrices = prices
+	}
+
+	repoTimeouts, err := parseRepoMap(os.Getenv("TASK_TIMEOUT_REPOS"), time.ParseDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TASK_TIMEOUT_REPOS: %w", err)
+	}
+	cfg.Timeouts.PerRepo = repoTimeouts
+
+	workspacePaths, err := parseRepoMap(os.Getenv("WORKSPACE_REPO_PATHS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse WORKSPACE_REPO_PATHS: %w", err)
+	}
+	cfg.WorkspacePaths = workspacePaths
+
+	repoModels, err := parseRepoMap(os.Getenv("MODEL_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MODEL_REPOS: %w", err)
+	}
+	cfg.Models.PerRepo = repoModels
+
+	repoBackends, err := parseRepoMap(os.Getenv("AGENT_BACKEND_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AGENT_BACKEND_REPOS: %w", err)
+	}
+	cfg.RepoBackends = repoBackends
+
+	repoSigningFormat, err := parseRepoMap(os.Getenv("COMMIT_SIGNING_FORMAT_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse COMMIT_SIGNING_FORMAT_REPOS: %w", err)
+	}
+	cfg.RepoSigningFormat = repoSigningFormat
+
+	repoSigningKey, err := parseRepoMap(os.Getenv("COMMIT_SIGNING_KEY_REPOS"), parseString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse COMMIT_SIGNING_KEY_REPOS: %w", err)
+	}
+	cfg.RepoSigningKey = repoSigningKey
+
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
@@ -67,6 +290,47 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// loadTargets applies REPO_TARGET_DIRS, REPO_TARGET_PROMPTS and
+// REPO_TARGET_TESTS to the sub-project targets in GITHUB_REPOS. Prompts and
+// test commands are separated by ';' since they may contain commas.
+func (c *Config) loadTargets() error {
+	dirs, err := parseRepoMap(os.Getenv("REPO_TARGET_DIRS"), parseString)
+	if err != nil {
+		return fmt.Errorf("failed to parse REPO_TARGET_DIRS: %w", err)
+	}
+	prompts, err := parseRepoMapSep(os.Getenv("REPO_TARGET_PROMPTS"), ";", parseString)
+	if err != nil {
+		return fmt.Errorf("failed to parse REPO_TARGET_PROMPTS: %w", err)
+	}
+	tests, err := parseRepoMapSep(os.Getenv("REPO_TARGET_TESTS"), ";", parseString)
+	if err != nil {
+		return fmt.Errorf("failed to parse REPO_TARGET_TESTS: %w", err)
+	}
+
+	for env, m := range map[string]map[string]string{"REPO_TARGET_DIRS": dirs, "REPO_TARGET_PROMPTS": prompts, "REPO_TARGET_TESTS": tests} {
+		for key := range m {
+			if repo := domain.FindRepository(c.Repositories, key); repo == nil || repo.Target.Name == "" {
+				return fmt.Errorf("%s: %s is not a target in GITHUB_REPOS", env, key)
+			}
+		}
+	}
+
+	for _, repo := range c.Repositories {
+		if repo.Target.Name == "" {
+			continue
+		}
+		if dir, ok := dirs[repo.Key()]; ok {
+			repo.Target.Dir = dir
+		}
+		if filepath.IsAbs(repo.Target.Dir) || strings.HasPrefix(filepath.Clean(repo.Target.Dir), "..") {
+			return fmt.Errorf("%s: target directory %q must be inside the repository", repo.Key(), repo.Target.Dir)
+		}
+		repo.Target.Prompt = prompts[repo.Key()]
+		repo.Target.TestCommand = tests[repo.Key()]
+	}
+	return nil
+}
+
 func (c *Config) loadRepositories() error {
 	reposEnv := os.Getenv("GITHUB_REPOS")
 
@@ -132,70 +396,133 @@ func (c *Config) autoDetectRepositories() ([]*domain.Repository, error) {
 		if !entry.IsDir() {
 			continue
 		}
-
 		repoPath := filepath.Join(c.WorkspacePath, entry.Name())
-		gitDir := filepath.Join(repoPath, ".git")
-
-		// Check if it's a Git repository
-		if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		found := c.scanRepositories(repoPath, maxOwnerDepth)
+		if len(found) == 0 {
 			fmt.Fprintf(os.Stderr, "Skipping %s: not a Git repository\n", entry.Name())
-			continue
 		}
+		repos = append(repos, found...)
+	}
 
-		fmt.Fprintf(os.Stderr, "Detecting repository: %s\n", entry.Name())
+	fmt.Fprintf(os.Stderr, "Auto-detected %d repositories\n", len(repos))
 
-		// Extract owner/repo from .git/config
-		owner, name, err := c.extractRepoInfo(repoPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to extract repo info from %s: %v\n", repoPath, err)
-			continue
-		}
+	return repos, nil
+}
 
-		// Get default branch
-		defaultBranch, err := c.getDefaultBranch(repoPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to get default branch for %s: %v, using 'main'\n", repoPath, err)
-			defaultBranch = "main"
+// maxOwnerDepth is how many directory levels auto-detection descends looking
+// for <owner>/<name> checkouts (GitLab owners may include subgroups).
+const maxOwnerDepth = 3
+
+// scanRepositories detects the checkout at dir or, if dir is not one, the
+// checkouts below it down to depth more levels.
+func (c *Config) scanRepositories(dir string, depth int) []*domain.Repository {
+	if isGitRepo(dir) {
+		if repo := c.detectRepository(dir); repo != nil {
+			return []*domain.Repository{repo}
 		}
+		return nil
+	}
+	if depth == 0 {
+		return nil
+	}
 
-		repo := &domain.Repository{
-			Owner:         owner,
-			Name:          name,
-			DefaultBranch: defaultBranch,
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", dir, err)
+		return nil
+	}
+	var repos []*domain.Repository
+	for _, child := range children {
+		if child.IsDir() && !strings.HasPrefix(child.Name(), ".") {
+			repos = append(repos, c.scanRepositories(filepath.Join(dir, child.Name()), depth-1)...)
 		}
-		repos = append(repos, repo)
-		fmt.Fprintf(os.Stderr, "Detected: %s/%s (branch: %s)\n", owner, name, defaultBranch)
 	}
+	return repos
+}
 
-	fmt.Fprintf(os.Stderr, "Auto-detected %d repositories\n", len(repos))
+func isGitRepo(path string) bool {
+	info, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil && info.IsDir()
+}
 
-	return repos, nil
+// detectRepository reads owner, name, git host and default branch of the
+// checkout at repoPath.
+func (c *Config) detectRepository(repoPath string) *domain.Repository {
+	fmt.Fprintf(os.Stderr, "Detecting repository: %s\n", repoPath)
+
+	// Extract owner/repo from .git/config
+	remote, err := c.extractRepoInfo(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to extract repo info from %s: %v\n", repoPath, err)
+		return nil
+	}
+
+	// Get default branch
+	defaultBranch, err := c.getDefaultBranch(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to get default branch for %s: %v, using 'main'\n", repoPath, err)
+		defaultBranch = "main"
+	}
+
+	// Git host, unless configured explicitly
+	host, hostURL := c.detectGitHost(remote)
+	if _, ok := c.RepoGitHosts[remote.Key()]; !ok && host != "" {
+		c.RepoGitHosts[remote.Key()] = host
+		if _, ok := c.RepoGitHostURLs[remote.Key()]; !ok && hostURL != "" {
+			c.RepoGitHostURLs[remote.Key()] = hostURL
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Detected: %s (branch: %s, host: %s)\n", remote.Key(), defaultBranch, c.GitHostFor(remote.Key()))
+	return &domain.Repository{
+		Owner:         remote.Owner,
+		Name:          remote.Name,
+		DefaultBranch: defaultBranch,
+	}
 }
 
-// extractRepoInfo extracts owner and repo name from .git/config
-func (c *Config) extractRepoInfo(repoPath string) (owner, name string, err error) {
+// extractRepoInfo parses the origin remote from .git/config
+func (c *Config) extractRepoInfo(repoPath string) (githost.Remote, error) {
 	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get remote URL: %w", err)
+		return githost.Remote{}, fmt.Errorf("failed to get remote URL: %w", err)
 	}
 
-	remoteURL := strings.TrimSpace(string(output))
+	// HTTPS: https://gitlab.example.com/group/subgroup/repo.git
+	// SSH: git@github.com:owner/repo.git, ssh://git@host:2222/owner/repo.git
+	return githost.ParseRemote(string(output))
+}
 
-	// Parse GitHub URL (supports both HTTPS and SSH formats)
-	// HTTPS: https://github.com/owner/repo.git
-	// SSH: git@github.com:owner/repo.git
-	re := regexp.MustCompile(`github\.com[:/]([^/]+)/(.+?)(\.git)?$`)
-	matches := re.FindStringSubmatch(remoteURL)
-	if len(matches) < 3 {
-		return "", "", fmt.Errorf("invalid GitHub URL format: %s", remoteURL)
+// detectGitHost guesses the git host of a remote: github.com, gitlab.com, the
+// server at GIT_HOST_URL, or a self-hosted server. Self-hosted servers are
+// GitLab or GitHub Enterprise if their hostname says so and otherwise of type
+// GIT_HOST. It returns "" for local remotes, leaving them on GIT_HOST.
+func (c *Config) detectGitHost(remote githost.Remote) (name, baseURL string) {
+	switch {
+	case remote.Host == "":
+		return "", ""
+	case remote.Host == "github.com":
+		return githost.HostGitHub, "https://github.com"
+	case remote.Host == "gitlab.com":
+		return githost.HostGitLab, "https://gitlab.com"
+	case c.GitHostURL != "" && hostnameOf(c.GitHostURL) == remote.Host:
+		return c.GitHost, ""
+	case strings.Contains(remote.Host, "gitlab"):
+		return githost.HostGitLab, "https://" + remote.Host
+	case strings.Contains(remote.Host, "github"):
+		return githost.HostGitHub, "https://" + remote.Host
 	}
+	return c.GitHost, "https://" + remote.Host
+}
 
-	owner = matches[1]
-	name = strings.TrimSuffix(matches[2], ".git")
-
-	return owner, name, nil
+func hostnameOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
 }
 
 // getDefaultBranch gets the default branch for a repository
@@ -248,9 +575,68 @@ func (c *Config) validate() error {
 		return fmt.Errorf("no default repository set")
 	}
 
+	if c.CommitAttribution != domain.AttributionBot && c.CommitAttribution != domain.AttributionUser {
+		return fmt.Errorf("invalid COMMIT_ATTRIBUTION %q: must be bot or user", c.CommitAttribution)
+	}
+
+	for _, repo := range c.Repositories {
+		if err := c.SigningFor(repo.Key()).Validate(); err != nil {
+			return fmt.Errorf("%s: %w (see COMMIT_SIGNING_*)", repo.Key(), err)
+		}
+	}
+
+	if c.WorkspaceDirty != "stash" && c.WorkspaceDirty != "refuse" {
+		return fmt.Errorf("invalid WORKSPACE_DIRTY %q: must be stash or refuse", c.WorkspaceDirty)
+	}
+
 	return nil
 }
 
+// SigningFor returns the commit signing configuration of a repository. A
+// per-repository format of "none" disables signing for it.
+func (c *Config) SigningFor(repoKey string) signing.Config {
+	cfg := c.Signing
+	if f, ok := domain.LookupRepo(c.RepoSigningFormat, repoKey); ok && f != "" {
+		if f == "none" {
+			return signing.Config{}
+		}
+		cfg.Format = signing.Format(f)
+	}
+	if k, ok := domain.LookupRepo(c.RepoSigningKey, repoKey); ok && k != "" {
+		cfg.Key = k
+	}
+	return cfg
+}
+
+// GitHostURLFor returns the base URL of the git host of a repository ("" for
+// the host's public server). GIT_HOST_URL only applies to repositories on
+// GIT_HOST.
+func (c *Config) GitHostURLFor(repoKey string) string {
+	if u, ok := domain.LookupRepo(c.RepoGitHostURLs, repoKey); ok && u != "" {
+		return u
+	}
+	if c.GitHostFor(repoKey) != c.GitHost {
+		return ""
+	}
+	return c.GitHostURL
+}
+
+// GitHostFor returns the name of the git host of a repository.
+func (c *Config) GitHostFor(repoKey string) string {
+	if h, ok := domain.LookupRepo(c.RepoGitHosts, repoKey); ok && h != "" {
+		return h
+	}
+	return c.GitHost
+}
+
+// BackendFor returns the name of the agent backend used for a repository.
+func (c *Config) BackendFor(repoKey string) string {
+	if b, ok := domain.LookupRepo(c.RepoBackends, repoKey); ok && b != "" {
+		return b
+	}
+	return c.Backend
+}
+
 func getEnvDefault(key, defaultVal string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -269,3 +655,89 @@ func getEnvIntDefault(key string, defaultVal int) int {
 	}
 	return n
 }
+
+func getEnvFloatDefault(key string, defaultVal float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return defaultVal
+	}
+	return f
+}
+
+func getEnvDurationDefault(key string, defaultVal time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return defaultVal
+	}
+	return d
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+// parsePrice parses "input/output" USD per million tokens, e.g. "15/75".
+func parsePrice(s string) (budget.Price, error) {
+	in, out, ok := strings.Cut(s, "/")
+	if !ok {
+		return budget.Price{}, fmt.Errorf("invalid price %q (expected input/output, e.g. 15/75)", s)
+	}
+	input, err := strconv.ParseFloat(strings.TrimSpace(in), 64)
+	if err != nil {
+		return budget.Price{}, err
+	}
+	output, err := strconv.ParseFloat(strings.TrimSpace(out), 64)
+	if err != nil {
+		return budget.Price{}, err
+	}
+	return budget.Price{Input: input, Output: output}, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRepoMap parses "owner/repo=value,owner/other=value" into a map keyed by owner/repo.
+func parseRepoMap[T any](v string, parse func(string) (T, error)) (map[string]T, error) {
+	return parseRepoMapSep(v, ",", parse)
+}
+
+// parseRepoMapSep is parseRepoMap with a custom entry separator.
+func parseRepoMapSep[T any](v, sep string, parse func(string) (T, error)) (map[string]T, error) {
+	result := make(map[string]T)
+	for _, part := range strings.Split(v, sep) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry: %s (expected owner/repo=value)", part)
+		}
+		parsed, err := parse(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in %s: %w", part, err)
+		}
+		result[strings.TrimSpace(key)] = parsed
+	}
+	return result, nil
+}
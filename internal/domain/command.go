@@ -1,6 +1,9 @@
 package domain
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type Command int
 
@@ -11,12 +14,52 @@ const (
 	CommandImplement
 	CommandSwitch
 	CommandRepos
-	CommandSync  // 順次実行モード
-	CommandAsync // 並列実行モード
-	CommandPRs   // PR一覧表示
-	CommandStop  // 緊急停止
+	CommandSync       // 順次実行モード
+	CommandAsync      // 並列実行モード
+	CommandPRs        // PR一覧表示
+	CommandStop       // 緊急停止
+	CommandUsage      // 利用状況レポート
+	CommandResume     // 中断されたタスクの再開
+	CommandStatus     // 管理者向けステータス表示
+	CommandTranscript // 直近の実行のトランスクリプトをアップロード
+	CommandPRStatus   // PRのレビュー・チェック状況表示
 )
 
+func (c Command) String() string {
+	switch c {
+	case CommandEnd:
+		return "end"
+	case CommandReview:
+		return "review"
+	case CommandImplement:
+		return "implement"
+	case CommandSwitch:
+		return "switch"
+	case CommandRepos:
+		return "repos"
+	case CommandSync:
+		return "sync"
+	case CommandAsync:
+		return "async"
+	case CommandPRs:
+		return "prs"
+	case CommandStop:
+		return "stop"
+	case CommandUsage:
+		return "usage"
+	case CommandResume:
+		return "resume"
+	case CommandStatus:
+		return "status"
+	case CommandTranscript:
+		return "transcript"
+	case CommandPRStatus:
+		return "pr status"
+	default:
+		return "none"
+	}
+}
+
 // DetectCommand detects special commands in the message text.
 func DetectCommand(text string) Command {
 	lower := strings.ToLower(strings.TrimSpace(text))
@@ -61,11 +104,37 @@ func DetectCommand(text string) Command {
 		return CommandAsync
 	}
 
+	// Show the reviews and checks of a pull request
+	if ExtractPRNumber(lower) > 0 {
+		return CommandPRStatus
+	}
+
 	// List pull requests
-	if lower == "prs" || lower == "pr" || lower == "pr list" || lower == "プルリク" {
+	if lower == "prs" || lower == "pr" || lower == "pr list" || lower == "プルリク" ||
+		lower == "mrs" || lower == "mr" || lower == "mr list" {
 		return CommandPRs
 	}
 
+	// Resume an interrupted task
+	if lower == "resume" || lower == "再開" {
+		return CommandResume
+	}
+
+	// Admin status dashboard
+	if lower == "status" || lower == "ステータス" || lower == "状態" {
+		return CommandStatus
+	}
+
+	// Upload the last run's transcript
+	if lower == "transcript" || lower == "トランスクリプト" {
+		return CommandTranscript
+	}
+
+	// Usage report
+	if _, ok := parseUsage(lower); ok {
+		return CommandUsage
+	}
+
 	return CommandNone
 }
 
@@ -92,3 +161,47 @@ func ExtractSwitchTarget(text string) string {
 
 	return ""
 }
+
+// ExtractUsageDays extracts the report period from a usage command (e.g., "usage 30").
+// Returns defaultDays if no number is given.
+func ExtractUsageDays(text string, defaultDays int) int {
+	days, ok := parseUsage(strings.ToLower(strings.TrimSpace(text)))
+	if !ok || days == 0 {
+		return defaultDays
+	}
+	return days
+}
+
+// parseUsage parses exactly "usage", "usage <N>" or "usage <N>d" (or "利用状況",
+// "利用状況 <N>", "利用状況 <N>日"), so instructions that merely start with the
+// word are not taken for the command. days is 0 when no period is given.
+func parseUsage(lower string) (days int, ok bool) {
+	fields := strings.Fields(lower)
+	if len(fields) == 0 || len(fields) > 2 || (fields[0] != "usage" && fields[0] != "利用状況") {
+		return 0, false
+	}
+	if len(fields) == 1 {
+		return 0, true
+	}
+	arg := strings.TrimSuffix(strings.TrimSuffix(fields[1], "d"), "日")
+	days, err := strconv.Atoi(arg)
+	if err != nil || days <= 0 {
+		return 0, false
+	}
+	return days, true
+}
+
+// ExtractPRNumber extracts the pull request number from a PR status command
+// (e.g., "pr 42", "pr #42" or, for GitLab merge requests, "mr !42"). Returns 0
+// if text is not such a command.
+func ExtractPRNumber(text string) int {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) != 2 || (fields[0] != "pr" && fields[0] != "mr" && fields[0] != "プルリク") {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimLeft(fields[1], "#!"))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
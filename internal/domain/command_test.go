@@ -0,0 +1,68 @@
+package domain
+
+import "testing"
+
+func TestDetectCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want Command
+	}{
+		{"stop", CommandStop},
+		{" 停止 ", CommandStop},
+		{"end", CommandEnd},
+		{"おわり", CommandEnd},
+		{"review", CommandReview},
+		{"implement", CommandImplement},
+		{"switch backend", CommandSwitch},
+		{"切り替え your-org/frontend", CommandSwitch},
+		{"repos", CommandRepos},
+		{"sync", CommandSync},
+		{"並列", CommandAsync},
+		{"prs", CommandPRs},
+		{"mr list", CommandPRs},
+		{"pr 42", CommandPRStatus},
+		{"pr #42", CommandPRStatus},
+		{"mr !7", CommandPRStatus},
+		{"resume", CommandResume},
+		{"status", CommandStatus},
+		{"transcript", CommandTranscript},
+		{"usage", CommandUsage},
+		{"Usage 30", CommandUsage},
+		{"usage 30d", CommandUsage},
+		{"利用状況", CommandUsage},
+		{"利用状況 14日", CommandUsage},
+
+		// Instructions that only start like a command
+		{"usage of the deprecated API in foo.go を直して", CommandNone},
+		{"usage 30 days", CommandNone},
+		{"usage 0", CommandNone},
+		{"利用状況ページを追加して", CommandNone},
+		{"pr 42 をレビューして", CommandNone},
+		{"stop the server gracefully", CommandNone},
+		{"READMEを更新して", CommandNone},
+	}
+	for _, tt := range tests {
+		if got := DetectCommand(tt.text); got != tt.want {
+			t.Errorf("DetectCommand(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtractUsageDays(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"usage", 7},
+		{"usage 30", 30},
+		{"usage 30d", 30},
+		{"利用状況 14日", 14},
+		{"usage abc", 7},
+		{"usage -3", 7},
+	}
+	for _, tt := range tests {
+		if got := ExtractUsageDays(tt.text, 7); got != tt.want {
+			t.Errorf("ExtractUsageDays(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
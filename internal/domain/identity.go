@@ -0,0 +1,24 @@
+package domain
+
+import "fmt"
+
+// GitIdentity is the name and email of a commit author or committer.
+type GitIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// String returns "Name <email>", the format of Co-Authored-By trailers.
+func (id GitIdentity) String() string {
+	return fmt.Sprintf("%s <%s>", id.Name, id.Email)
+}
+
+// AttributionMode decides who authors a task's commits.
+type AttributionMode string
+
+const (
+	// AttributionBot: the bot authors commits, Co-Authored-By the requesting user
+	AttributionBot AttributionMode = "bot"
+	// AttributionUser: the requesting user authors commits, Co-Authored-By the bot
+	AttributionUser AttributionMode = "user"
+)
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// InlineOptions are per-task options given as key=value tokens at the start of
+// the instruction, e.g. "timeout=60m READMEを更新して".
+type InlineOptions struct {
+	Timeout time.Duration
+	Model   string   // must be allowed by the ModelPolicy
+	Repos   []string // repos=a,b: run across these repositories, the first one is primary
+}
+
+// inlineOptionRe matches a whole known key=value option token.
+var inlineOptionRe = regexp.MustCompile(`(?i)^(timeout|model|repos)=(\S*)$`)
+
+// ParseInlineOptions extracts the leading run of known key=value options from
+// the instruction and stops at the first token that is not one, so options
+// mentioned later in the text (e.g. "set timeout=5s in config.go") are left
+// alone. It returns the options and the rest of the instruction.
+func ParseInlineOptions(text string) (InlineOptions, string, error) {
+	var opts InlineOptions
+
+	rest := strings.TrimLeftFunc(text, unicode.IsSpace)
+	for rest != "" {
+		token := rest
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			token = rest[:i]
+		}
+		m := inlineOptionRe.FindStringSubmatch(token)
+		if m == nil {
+			break
+		}
+		key, value := strings.ToLower(m[1]), m[2]
+
+		switch key {
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return opts, text, fmt.Errorf("invalid timeout %q (e.g. timeout=60m)", value)
+			}
+			opts.Timeout = d
+		case "model":
+			if value == "" {
+				return opts, text, fmt.Errorf("empty model (e.g. model=claude-sonnet-4-5)")
+			}
+			opts.Model = value
+		case "repos":
+			opts.Repos = nil
+			for _, r := range strings.Split(value, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					opts.Repos = append(opts.Repos, r)
+				}
+			}
+			if len(opts.Repos) == 0 {
+				return opts, text, fmt.Errorf("empty repos (e.g. repos=backend,frontend)")
+			}
+		}
+		rest = strings.TrimLeftFunc(rest[len(token):], unicode.IsSpace)
+	}
+
+	return opts, strings.TrimSpace(rest), nil
+}
+
+// TimeoutPolicy decides how long a task may run.
+type TimeoutPolicy struct {
+	Default time.Duration // global default
+	Min     time.Duration // lower bound for user overrides (0 = no bound)
+	Max     time.Duration // upper bound for user overrides (0 = no bound)
+	Warning time.Duration // warn this long before the deadline (0 = no warning)
+	PerMode map[AgentMode]time.Duration
+	PerRepo map[string]time.Duration // key: owner/name
+}
+
+// Resolve returns the timeout for a task. A user override takes precedence but is
+// clamped to [Min, Max] (clamped reports whether that happened); otherwise the
+// repository setting, then the mode setting, then the global default is used.
+func (p TimeoutPolicy) Resolve(repoKey string, mode AgentMode, override time.Duration) (timeout time.Duration, clamped bool) {
+	if override > 0 {
+		if p.Max > 0 && override > p.Max {
+			return p.Max, true
+		}
+		if p.Min > 0 && override < p.Min {
+			return p.Min, true
+		}
+		return override, false
+	}
+	if d, ok := LookupRepo(p.PerRepo, repoKey); ok && d > 0 {
+		return d, false
+	}
+	if d, ok := p.PerMode[mode]; ok && d > 0 {
+		return d, false
+	}
+	return p.Default, false
+}
+
+// ModelPolicy decides which model a task runs with. An empty model means the
+// backend's default.
+type ModelPolicy struct {
+	Default  string
+	PerMode  map[AgentMode]string
+	PerRepo  map[string]string // key: owner/name
+	Allowed  []string          // models users may pick with model=...; empty disables overrides
+	Fallback string            // used by the backend when the model is overloaded
+}
+
+// Resolve returns the model for a task. A user override takes precedence if it
+// is allowed (ok is false otherwise); then the repository setting, then the
+// mode setting, then the default.
+func (p ModelPolicy) Resolve(repoKey string, mode AgentMode, override string) (model string, ok bool) {
+	if override != "" {
+		for _, m := range p.Allowed {
+			if m == override {
+				return override, true
+			}
+		}
+		return "", false
+	}
+	if m, _ := LookupRepo(p.PerRepo, repoKey); m != "" {
+		return m, true
+	}
+	if m := p.PerMode[mode]; m != "" {
+		return m, true
+	}
+	return p.Default, true
+}
+
+// RetryPolicy decides how transient backend failures are retried.
+type RetryPolicy struct {
+	Max        int           // retries after the first attempt (0 = no retries)
+	Backoff    time.Duration // wait before the first retry; doubled for each further one
+	MaxBackoff time.Duration // upper bound for the wait (0 = no bound)
+}
+
+// Delay returns how long to wait before the given retry (1-based).
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInlineOptions(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    InlineOptions
+		rest    string
+		wantErr bool
+	}{
+		{text: "READMEを更新して", rest: "READMEを更新して"},
+		{text: "timeout=60m READMEを更新して", want: InlineOptions{Timeout: time.Hour}, rest: "READMEを更新して"},
+		{text: "  TIMEOUT=90s\tmodel=claude-opus-4-1 fix it", want: InlineOptions{Timeout: 90 * time.Second, Model: "claude-opus-4-1"}, rest: "fix it"},
+		{text: "repos=backend,,frontend API を追加して", want: InlineOptions{Repos: []string{"backend", "frontend"}}, rest: "API を追加して"},
+		{text: "timeout=5m\n複数行の\n指示", want: InlineOptions{Timeout: 5 * time.Minute}, rest: "複数行の\n指示"},
+		{text: "timeout=5m", want: InlineOptions{Timeout: 5 * time.Minute}, rest: ""},
+
+		// Options are only read at the start of the instruction
+		{text: "set the http client timeout=5s in config.go", rest: "set the http client timeout=5s in config.go"},
+		{text: "rename the `timeout=` flag", rest: "rename the `timeout=` flag"},
+		{text: "add a model=foo field to the Django form", rest: "add a model=foo field to the Django form"},
+		{text: "timeout=10m then use model=x here", want: InlineOptions{Timeout: 10 * time.Minute}, rest: "then use model=x here"},
+		{text: "foo=bar timeout=10m fix", rest: "foo=bar timeout=10m fix"},
+
+		// Invalid leading options
+		{text: "timeout=abc fix", wantErr: true},
+		{text: "timeout=-5m fix", wantErr: true},
+		{text: "timeout= fix", wantErr: true},
+		{text: "model= fix", wantErr: true},
+		{text: "repos=, fix", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, rest, err := ParseInlineOptions(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseInlineOptions(%q): want an error", tt.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseInlineOptions(%q): %v", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || rest != tt.rest {
+			t.Errorf("ParseInlineOptions(%q) = %+v, %q; want %+v, %q", tt.text, got, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestTimeoutPolicyResolve(t *testing.T) {
+	p := TimeoutPolicy{
+		Default: 30 * time.Minute,
+		Min:     time.Minute,
+		Max:     2 * time.Hour,
+		PerMode: map[AgentMode]time.Duration{ModeReview: 15 * time.Minute},
+		PerRepo: map[string]time.Duration{"org/backend": time.Hour},
+	}
+	tests := []struct {
+		repo        string
+		mode        AgentMode
+		override    time.Duration
+		want        time.Duration
+		wantClamped bool
+	}{
+		{"org/web", ModeImplementation, 0, 30 * time.Minute, false},
+		{"org/web", ModeReview, 0, 15 * time.Minute, false},
+		{"org/backend", ModeReview, 0, time.Hour, false},
+		{"org/web", ModeImplementation, 45 * time.Minute, 45 * time.Minute, false},
+		{"org/web", ModeImplementation, 5 * time.Hour, 2 * time.Hour, true},
+		{"org/web", ModeImplementation, 5 * time.Second, time.Minute, true},
+	}
+	for _, tt := range tests {
+		got, clamped := p.Resolve(tt.repo, tt.mode, tt.override)
+		if got != tt.want || clamped != tt.wantClamped {
+			t.Errorf("Resolve(%s, %s, %s) = %s, %v; want %s, %v", tt.repo, tt.mode, tt.override, got, clamped, tt.want, tt.wantClamped)
+		}
+	}
+}
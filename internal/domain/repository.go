@@ -5,21 +5,58 @@ import (
 	"strings"
 )
 
-// Repository represents a GitHub repository configuration.
+// Repository represents a GitHub repository configuration. A repository with
+// a Target is a sub-project of a monorepo; it shares the checkout with the
+// other targets of the same repository.
 type Repository struct {
 	Owner         string
 	Name          string
 	DefaultBranch string
+	Target        Target
 }
 
-// Key returns a unique identifier for the repository (owner/name).
+// Target is a named sub-project of a repository (e.g. "web" in org/mono#web).
+type Target struct {
+	Name        string // empty for the whole repository
+	Dir         string // working directory relative to the repository root
+	Prompt      string // extra instructions for the agent
+	TestCommand string // how to run the sub-project's tests
+}
+
+// Key returns a unique identifier for the repository (owner/name, or
+// owner/name#target for a sub-project).
 func (r *Repository) Key() string {
+	if r.Target.Name != "" {
+		return fmt.Sprintf("%s/%s#%s", r.Owner, r.Name, r.Target.Name)
+	}
+	return r.RepoKey()
+}
+
+// RepoKey returns owner/name without the target, i.e. the GitHub repository.
+func (r *Repository) RepoKey() string {
 	return fmt.Sprintf("%s/%s", r.Owner, r.Name)
 }
 
+// RepoKeyOf strips the target from a repository key ("org/mono#web" -> "org/mono").
+func RepoKeyOf(key string) string {
+	repoKey, _, _ := strings.Cut(key, "#")
+	return repoKey
+}
+
+// LookupRepo returns the setting for a repository key, falling back to the
+// setting of the whole repository for a sub-project key.
+func LookupRepo[T any](m map[string]T, key string) (T, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	v, ok := m[RepoKeyOf(key)]
+	return v, ok
+}
+
 // ParseRepositories parses a comma-separated list of repositories.
-// Format: "owner1/repo1:branch1,owner2/repo2:branch2,owner3/repo3"
-// Branch is optional; if omitted, defaultBranch is used.
+// Format: "owner1/repo1:branch1,owner2/repo2:branch2,owner3/repo3#target,group/subgroup/repo4"
+// Branch is optional; if omitted, defaultBranch is used. "#target" registers
+// a sub-project whose directory defaults to the target name.
 func ParseRepositories(reposEnv, defaultBranch string) ([]*Repository, error) {
 	if reposEnv == "" {
 		return nil, nil
@@ -42,14 +79,19 @@ func ParseRepositories(reposEnv, defaultBranch string) ([]*Repository, error) {
 			branch = strings.TrimSpace(repoBranch[1])
 		}
 
-		// Split owner/name
-		ownerName := strings.Split(repoPath, "/")
-		if len(ownerName) != 2 {
+		// Split off the sub-project target
+		repoPath, target, _ := strings.Cut(repoPath, "#")
+		target = strings.TrimSpace(target)
+
+		// Split owner/name at the last '/': GitLab owners may include
+		// subgroups (group/subgroup/name)
+		i := strings.LastIndex(repoPath, "/")
+		if i < 0 {
 			return nil, fmt.Errorf("invalid repository format: %s (expected owner/name)", part)
 		}
 
-		owner := strings.TrimSpace(ownerName[0])
-		name := strings.TrimSpace(ownerName[1])
+		owner := strings.TrimSpace(repoPath[:i])
+		name := strings.TrimSpace(repoPath[i+1:])
 
 		if owner == "" || name == "" {
 			return nil, fmt.Errorf("invalid repository format: %s (owner and name cannot be empty)", part)
@@ -59,6 +101,7 @@ func ParseRepositories(reposEnv, defaultBranch string) ([]*Repository, error) {
 			Owner:         owner,
 			Name:          name,
 			DefaultBranch: branch,
+			Target:        Target{Name: target, Dir: target},
 		})
 	}
 
@@ -74,3 +117,35 @@ func FindRepository(repos []*Repository, key string) *Repository {
 	}
 	return nil
 }
+
+// MatchRepositories returns the repositories a user-typed target refers to.
+// An exact key (case-insensitive) wins; otherwise the target is matched
+// against repository and sub-project names, then as a substring of the key. More than one
+// result means the target is ambiguous.
+func MatchRepositories(repos []*Repository, target string) []*Repository {
+	target = strings.ToLower(strings.TrimSpace(target))
+	if target == "" {
+		return nil
+	}
+
+	for _, repo := range repos {
+		if strings.ToLower(repo.Key()) == target {
+			return []*Repository{repo}
+		}
+	}
+
+	var byName, byPart []*Repository
+	for _, repo := range repos {
+		key := strings.ToLower(repo.Key())
+		switch {
+		case strings.ToLower(repo.Name) == target, strings.ToLower(repo.Target.Name) == target:
+			byName = append(byName, repo)
+		case strings.Contains(key, target):
+			byPart = append(byPart, repo)
+		}
+	}
+	if len(byName) > 0 {
+		return byName
+	}
+	return byPart
+}
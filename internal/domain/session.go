@@ -53,6 +53,7 @@ type Session struct {
 	Mode          AgentMode
 	ExecutionMode ExecutionMode // Sync or Async execution
 	Repository    *Repository   // Current repository for this session
+	Related       []*Repository // Other repositories of a multi-repository session (repos=a,b)
 	IsRunning     bool
 	IsActive      bool
 	StatusMsgTS   string
@@ -117,10 +118,27 @@ func (s *Session) Active() bool {
 	return s.IsActive
 }
 
+// SetRepository switches the session to a single repository.
 func (s *Session) SetRepository(repo *Repository) {
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
 	s.Repository = repo
+	s.Related = nil
+}
+
+// SetRepositories makes the session span several repositories; the agent runs
+// in primary and has access to related.
+func (s *Session) SetRepositories(primary *Repository, related []*Repository) {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	s.Repository = primary
+	s.Related = related
+}
+
+func (s *Session) GetRelated() []*Repository {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	return s.Related
 }
 
 func (s *Session) GetRepository() *Repository {
@@ -0,0 +1,76 @@
+// Package gitenv builds the git environment of agent runs: config entries
+// passed as GIT_CONFIG_* variables, and the hooks their commits run through.
+package gitenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Setting is a git config entry, e.g. {"commit.gpgsign", "true"}.
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Env returns the GIT_CONFIG_COUNT/KEY_n/VALUE_n variables that apply
+// settings to every git command of a process. All settings must go through
+// a single call: a second GIT_CONFIG_COUNT would replace the first.
+func Env(settings []Setting) []string {
+	if len(settings) == 0 {
+		return nil
+	}
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(settings))}
+	for i, s := range settings {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, s.Key),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, s.Value))
+	}
+	return env
+}
+
+// hookNames are the client-side hooks installed, so a checkout's own hooks
+// keep running when core.hooksPath points at the installed ones.
+var hookNames = []string{
+	"applypatch-msg", "pre-applypatch", "post-applypatch",
+	"pre-commit", "pre-merge-commit", "prepare-commit-msg", "commit-msg", "post-commit",
+	"pre-rebase", "post-checkout", "post-merge", "pre-push", "post-rewrite", "pre-auto-gc",
+}
+
+// hookScript adds the $CO_AUTHORED_BY trailer to commit messages, then runs
+// the checkout's own hook of the same name, if any.
+const hookScript = `#!/bin/sh
+# Installed by slack-claude-agent (core.hooksPath); see internal/gitenv.
+name=$(basename "$0")
+if [ "$name" = commit-msg ] && [ -n "$CO_AUTHORED_BY" ]; then
+	git interpret-trailers --in-place --if-exists addIfDifferent --trailer "$CO_AUTHORED_BY" "$1" || exit 1
+fi
+hooks=$(git config --local --get core.hooksPath || git config --global --get core.hooksPath || echo "$(git rev-parse --git-common-dir)/hooks")
+[ -x "$hooks/$name" ] || exit 0
+exec "$hooks/$name" "$@"
+`
+
+// InstallHooks writes the hooks into dir and returns its absolute path, to
+// be set as core.hooksPath.
+func InstallHooks(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create hooks dir: %w", err)
+	}
+	for _, name := range hookNames {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(hookScript), 0o755); err != nil {
+			return "", fmt.Errorf("write %s hook: %w", name, err)
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(path, 0o755); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
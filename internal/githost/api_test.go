@@ -0,0 +1,188 @@
+package githost
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// newAPIServer serves the given JSON bodies by escaped request path, answers
+// 404 to other paths and fails the test on a missing authentication header.
+func newAPIServer(t *testing.T, header, token string, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(header); got != token {
+			t.Errorf("%s %s: %s header = %q, want %q", r.Method, r.URL, header, got, token)
+		}
+		body, ok := bodies[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitLab(t *testing.T) {
+	ctx := context.Background()
+	project := "/api/v4/projects/group%2Fsubgroup%2Fproject"
+	srv := newAPIServer(t, "PRIVATE-TOKEN", "glpat-test", map[string]string{
+		project + "/merge_requests": `[
+			{"iid": 7, "title": "Fix typo", "web_url": "https://gitlab.example.com/group/subgroup/project/-/merge_requests/7",
+			 "source_branch": "fix-typo", "state": "opened", "draft": true, "author": {"username": "alice"},
+			 "created_at": "2026-10-01T09:30:00.000Z"},
+			{"iid": 6, "title": "Locked", "source_branch": "locked", "state": "locked", "author": {"username": "bob"},
+			 "created_at": "2026-09-30T09:30:00.000Z"}
+		]`,
+		project + "/merge_requests/7/approvals": `{"approved_by": [{"user": {"username": "bob"}}, {"user": {"username": "carol"}}]}`,
+		project + "/merge_requests/7/pipelines": `[{"id": 42}]`,
+		project + "/pipelines/42/jobs": `[
+			{"name": "build", "status": "success", "web_url": "https://gitlab.example.com/jobs/1"},
+			{"name": "test", "status": "failed"},
+			{"name": "flaky", "status": "failed", "allow_failure": true},
+			{"name": "deploy", "status": "manual"},
+			{"name": "lint", "status": "running"}
+		]`,
+	})
+	gl := NewGitLab(srv.URL+"/", "glpat-test")
+	repo := &domain.Repository{Owner: "group/subgroup", Name: "project", DefaultBranch: "main"}
+
+	prs, err := gl.ListPullRequests(ctx, repo, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PullRequest{
+		{
+			Number: 7, Title: "Fix typo", URL: "https://gitlab.example.com/group/subgroup/project/-/merge_requests/7",
+			Branch: "fix-typo", Author: "alice", State: "open", Draft: true,
+			CreatedAt: time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			Number: 6, Title: "Locked", Branch: "locked", Author: "bob", State: "open",
+			CreatedAt: time.Date(2026, 9, 30, 9, 30, 0, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(prs, want) {
+		t.Errorf("ListPullRequests() = %+v\nwant %+v", prs, want)
+	}
+
+	reviews, err := gl.Reviews(ctx, repo, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantReviews := []Review{{Author: "bob", State: ReviewApproved}, {Author: "carol", State: ReviewApproved}}
+	if !reflect.DeepEqual(reviews, wantReviews) {
+		t.Errorf("Reviews() = %+v, want %+v", reviews, wantReviews)
+	}
+
+	checks, err := gl.Checks(ctx, repo, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChecks := []Check{
+		{Name: "build", State: CheckSuccess, URL: "https://gitlab.example.com/jobs/1"},
+		{Name: "test", State: CheckFailure},
+		{Name: "flaky", State: CheckSkipped},
+		{Name: "deploy", State: CheckSkipped},
+		{Name: "lint", State: CheckPending},
+	}
+	if !reflect.DeepEqual(checks, wantChecks) {
+		t.Errorf("Checks() = %+v\nwant %+v", checks, wantChecks)
+	}
+
+	if _, err := gl.Reviews(ctx, repo, 8); err == nil {
+		t.Error("Reviews(!8): want an error for a 404 response")
+	}
+}
+
+func TestGitea(t *testing.T) {
+	ctx := context.Background()
+	pull := `{"number": 3, "title": "Add tests", "html_url": "https://gitea.example.com/org/repo/pulls/3",
+		"state": "open", "head": {"ref": "add-tests", "sha": "abc123"}, "user": {"login": "alice"},
+		"created_at": "2026-10-01T18:30:00+09:00"}`
+	srv := newAPIServer(t, "Authorization", "token gitea-test", map[string]string{
+		"/api/v1/repos/org/repo/pulls": `[` + pull + `,
+			{"number": 2, "title": "Old", "state": "closed", "merged": true, "head": {"ref": "old"}, "user": {"login": "bob"},
+			 "created_at": "2026-09-01T00:00:00Z"}
+		]`,
+		"/api/v1/repos/org/repo/pulls/3": pull,
+		"/api/v1/repos/org/repo/pulls/3/reviews": `[
+			{"state": "REQUEST_CHANGES", "user": {"login": "bob"}},
+			{"state": "PENDING", "user": {"login": "carol"}},
+			{"state": "COMMENT", "user": {"login": "carol"}},
+			{"state": "APPROVED", "user": {"login": "bob"}}
+		]`,
+		"/api/v1/repos/org/repo/commits/abc123/status": `{"statuses": [
+			{"context": "ci/build", "status": "success", "target_url": "https://ci.example.com/1"},
+			{"context": "ci/lint", "status": "warning"},
+			{"context": "ci/test", "status": "failure"},
+			{"context": "ci/e2e", "status": "error"},
+			{"context": "ci/deploy", "status": "pending"}
+		]}`,
+	})
+	gt := NewGitea(srv.URL, "gitea-test")
+	repo := &domain.Repository{Owner: "org", Name: "repo", DefaultBranch: "main"}
+
+	pr, err := gt.FindPullRequest(ctx, repo, "old")
+	if err != nil || pr == nil {
+		t.Fatalf("FindPullRequest(old) = %+v, %v", pr, err)
+	}
+	if pr.Number != 2 || pr.State != "merged" || pr.Author != "bob" {
+		t.Errorf("FindPullRequest(old) = %+v; want #2 merged by bob", pr)
+	}
+	if pr, err := gt.FindPullRequest(ctx, repo, "unknown"); err != nil || pr != nil {
+		t.Errorf("FindPullRequest(unknown) = %+v, %v; want nil", pr, err)
+	}
+
+	pr, err = gt.FindPullRequest(ctx, repo, "add-tests")
+	if err != nil || pr == nil {
+		t.Fatalf("FindPullRequest(add-tests) = %+v, %v", pr, err)
+	}
+	want := PullRequest{
+		Number: 3, Title: "Add tests", URL: "https://gitea.example.com/org/repo/pulls/3",
+		Branch: "add-tests", Author: "alice", State: "open",
+	}
+	if created := pr.CreatedAt; !created.Equal(time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("CreatedAt = %s, want 2026-10-01 09:30 UTC", created)
+	}
+	pr.CreatedAt = time.Time{}
+	if *pr != want {
+		t.Errorf("FindPullRequest(add-tests) = %+v\nwant %+v", *pr, want)
+	}
+
+	reviews, err := gt.Reviews(ctx, repo, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantReviews := []Review{
+		{Author: "bob", State: ReviewChangesRequested},
+		{Author: "carol", State: ReviewCommented},
+		{Author: "bob", State: ReviewApproved},
+	}
+	if !reflect.DeepEqual(reviews, wantReviews) {
+		t.Errorf("Reviews() = %+v\nwant %+v", reviews, wantReviews)
+	}
+
+	checks, err := gt.Checks(ctx, repo, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChecks := []Check{
+		{Name: "ci/build", State: CheckSuccess, URL: "https://ci.example.com/1"},
+		{Name: "ci/lint", State: CheckSuccess},
+		{Name: "ci/test", State: CheckFailure},
+		{Name: "ci/e2e", State: CheckFailure},
+		{Name: "ci/deploy", State: CheckPending},
+	}
+	if !reflect.DeepEqual(checks, wantChecks) {
+		t.Errorf("Checks() = %+v\nwant %+v", checks, wantChecks)
+	}
+}
@@ -0,0 +1,161 @@
+package githost
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Gitea talks to the Gitea (or Forgejo) REST API. Checks are the commit
+// statuses of the pull request's head commit.
+type Gitea struct {
+	baseURL string
+	api     apiClient
+}
+
+// NewGitea creates a Gitea host for baseURL (e.g. https://gitea.example.com)
+// authenticating with an access token.
+func NewGitea(baseURL, token string) *Gitea {
+	if token != "" {
+		token = "token " + token
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &Gitea{baseURL: baseURL, api: newAPIClient(baseURL+"/api/v1", "Authorization", token)}
+}
+
+func (g *Gitea) Name() string { return HostGitea }
+
+func (g *Gitea) Env() []string { return nil }
+
+func (g *Gitea) CloneURL(repo *domain.Repository) string {
+	return fmt.Sprintf("%s/%s.git", g.baseURL, repo.RepoKey())
+}
+
+type giteaPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"` // open, closed
+	Merged  bool   `json:"merged"`
+	Draft   bool   `json:"draft"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p giteaPull) normalize() PullRequest {
+	state := p.State
+	if p.Merged {
+		state = "merged"
+	}
+	return PullRequest{
+		Number: p.Number,
+		Title:  p.Title,
+		URL:    p.HTMLURL,
+		Branch: p.Head.Ref,
+		Author: p.User.Login,
+		State:  state,
+		Draft:  p.Draft,
+
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+func (g *Gitea) repoPath(repo *domain.Repository) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(repo.Owner), url.PathEscape(repo.Name))
+}
+
+func (g *Gitea) ListPullRequests(ctx context.Context, repo *domain.Repository, limit int) ([]PullRequest, error) {
+	var pulls []giteaPull
+	if err := g.api.get(ctx, fmt.Sprintf("%s/pulls?state=open&sort=newest&limit=%d", g.repoPath(repo), limit), &pulls); err != nil {
+		return nil, err
+	}
+	result := make([]PullRequest, len(pulls))
+	for i, p := range pulls {
+		result[i] = p.normalize()
+	}
+	return result, nil
+}
+
+// FindPullRequest looks through the 50 newest pull requests; the API cannot
+// filter by head branch.
+func (g *Gitea) FindPullRequest(ctx context.Context, repo *domain.Repository, branch string) (*PullRequest, error) {
+	var pulls []giteaPull
+	if err := g.api.get(ctx, fmt.Sprintf("%s/pulls?state=all&sort=newest&limit=50", g.repoPath(repo)), &pulls); err != nil {
+		return nil, err
+	}
+	for _, p := range pulls {
+		if p.Head.Ref == branch {
+			pr := p.normalize()
+			return &pr, nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *Gitea) Reviews(ctx context.Context, repo *domain.Repository, number int) ([]Review, error) {
+	var raw []struct {
+		State string `json:"state"`
+		User  struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := g.api.get(ctx, fmt.Sprintf("%s/pulls/%d/reviews", g.repoPath(repo), number), &raw); err != nil {
+		return nil, err
+	}
+	var reviews []Review
+	for _, r := range raw {
+		var state ReviewState
+		switch r.State {
+		case "APPROVED":
+			state = ReviewApproved
+		case "REQUEST_CHANGES":
+			state = ReviewChangesRequested
+		case "COMMENT":
+			state = ReviewCommented
+		default: // PENDING, REQUEST_REVIEW
+			continue
+		}
+		reviews = append(reviews, Review{Author: r.User.Login, State: state})
+	}
+	return reviews, nil
+}
+
+func (g *Gitea) Checks(ctx context.Context, repo *domain.Repository, number int) ([]Check, error) {
+	var pull giteaPull
+	if err := g.api.get(ctx, fmt.Sprintf("%s/pulls/%d", g.repoPath(repo), number), &pull); err != nil {
+		return nil, err
+	}
+
+	var combined struct {
+		Statuses []struct {
+			Context   string `json:"context"`
+			Status    string `json:"status"` // pending, success, error, failure, warning
+			TargetURL string `json:"target_url"`
+		} `json:"statuses"`
+	}
+	if err := g.api.get(ctx, fmt.Sprintf("%s/commits/%s/status", g.repoPath(repo), pull.Head.SHA), &combined); err != nil {
+		return nil, err
+	}
+	checks := make([]Check, len(combined.Statuses))
+	for i, s := range combined.Statuses {
+		state := CheckPending
+		switch s.Status {
+		case "success", "warning":
+			state = CheckSuccess
+		case "error", "failure":
+			state = CheckFailure
+		}
+		checks[i] = Check{Name: s.Context, State: state, URL: s.TargetURL}
+	}
+	return checks, nil
+}
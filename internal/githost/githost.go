@@ -0,0 +1,91 @@
+// Package githost abstracts the service hosting a repository's pull requests
+// (GitHub, GitLab, Gitea, or a local bare repository in tests and air-gapped
+// setups).
+package githost
+
+import (
+	"context"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Host names accepted by GIT_HOST and GIT_HOST_REPOS.
+const (
+	HostGitHub = "github"
+	HostGitLab = "gitlab"
+	HostGitea  = "gitea"
+	HostLocal  = "local"
+)
+
+// Host lists and inspects the pull requests of a repository.
+type Host interface {
+	// Name returns the host name (one of the Host* constants).
+	Name() string
+
+	// CloneURL returns the URL missing checkouts are cloned from.
+	CloneURL(repo *domain.Repository) string
+
+	// Env returns the environment variables that point the host's CLI (gh,
+	// glab) at this server, for the agent's subprocesses. Empty for the
+	// public server.
+	Env() []string
+
+	// ListPullRequests returns up to limit open pull requests, newest first.
+	ListPullRequests(ctx context.Context, repo *domain.Repository, limit int) ([]PullRequest, error)
+
+	// FindPullRequest returns the newest pull request (in any state) whose
+	// head is branch, or nil if there is none.
+	FindPullRequest(ctx context.Context, repo *domain.Repository, branch string) (*PullRequest, error)
+
+	// Reviews returns the reviews of a pull request.
+	Reviews(ctx context.Context, repo *domain.Repository, number int) ([]Review, error)
+
+	// Checks returns the CI checks of a pull request's head commit.
+	Checks(ctx context.Context, repo *domain.Repository, number int) ([]Check, error)
+}
+
+// PullRequest is a pull request (a merge request on GitLab).
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Branch string `json:"branch"` // head branch
+	Author string `json:"author"`
+	State  string `json:"state"` // "open", "closed" or "merged"
+	Draft  bool   `json:"draft,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReviewState is the normalized outcome of a review.
+type ReviewState string
+
+const (
+	ReviewApproved         ReviewState = "approved"
+	ReviewChangesRequested ReviewState = "changes_requested"
+	ReviewCommented        ReviewState = "commented"
+)
+
+// Review is a review of a pull request.
+type Review struct {
+	Author string      `json:"author"`
+	State  ReviewState `json:"state"`
+}
+
+// CheckState is the normalized state of a CI check.
+type CheckState string
+
+const (
+	CheckPending CheckState = "pending"
+	CheckSuccess CheckState = "success"
+	CheckFailure CheckState = "failure"
+	CheckSkipped CheckState = "skipped"
+)
+
+// Check is a CI check (a GitLab job, a Gitea commit status).
+type Check struct {
+	Name  string     `json:"name"`
+	State CheckState `json:"state"`
+	URL   string     `json:"url,omitempty"`
+}
@@ -0,0 +1,191 @@
+package githost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// GitHub talks to GitHub or a GitHub Enterprise server through the gh CLI,
+// reusing its authentication.
+type GitHub struct {
+	path     string // gh binary
+	hostname string // GitHub Enterprise server; "" for github.com
+}
+
+// NewGitHub creates a GitHub host. baseURL is the GitHub Enterprise server
+// (e.g. https://github.example.com); "" means github.com.
+func NewGitHub(path, baseURL string) *GitHub {
+	if path == "" {
+		path = "gh"
+	}
+	hostname := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		hostname = u.Hostname()
+	}
+	if hostname == "github.com" {
+		hostname = ""
+	}
+	return &GitHub{path: path, hostname: hostname}
+}
+
+func (g *GitHub) Name() string { return HostGitHub }
+
+func (g *GitHub) CloneURL(repo *domain.Repository) string {
+	hostname := g.hostname
+	if hostname == "" {
+		hostname = "github.com"
+	}
+	return fmt.Sprintf("https://%s/%s.git", hostname, repo.RepoKey())
+}
+
+func (g *GitHub) Env() []string {
+	if g.hostname == "" {
+		return nil
+	}
+	return []string{"GH_HOST=" + g.hostname}
+}
+
+// ghPullRequest is the subset of `gh pr list --json` output we use.
+type ghPullRequest struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	HeadRefName string `json:"headRefName"`
+	State       string `json:"state"`
+	IsDraft     bool   `json:"isDraft"`
+	Author      struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const ghPullRequestFields = "number,title,url,headRefName,state,isDraft,author,createdAt"
+
+func (p ghPullRequest) normalize() PullRequest {
+	return PullRequest{
+		Number: p.Number,
+		Title:  p.Title,
+		URL:    p.URL,
+		Branch: p.HeadRefName,
+		Author: p.Author.Login,
+		State:  strings.ToLower(p.State),
+		Draft:  p.IsDraft,
+
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+func (g *GitHub) ListPullRequests(ctx context.Context, repo *domain.Repository, limit int) ([]PullRequest, error) {
+	var prs []ghPullRequest
+	if err := g.run(ctx, &prs, "pr", "list", "--repo", repo.RepoKey(), "--limit", strconv.Itoa(limit), "--json", ghPullRequestFields); err != nil {
+		return nil, err
+	}
+	result := make([]PullRequest, len(prs))
+	for i, p := range prs {
+		result[i] = p.normalize()
+	}
+	return result, nil
+}
+
+func (g *GitHub) FindPullRequest(ctx context.Context, repo *domain.Repository, branch string) (*PullRequest, error) {
+	var prs []ghPullRequest
+	if err := g.run(ctx, &prs, "pr", "list", "--repo", repo.RepoKey(), "--head", branch, "--state", "all", "--limit", "1", "--json", ghPullRequestFields); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	pr := prs[0].normalize()
+	return &pr, nil
+}
+
+func (g *GitHub) Reviews(ctx context.Context, repo *domain.Repository, number int) ([]Review, error) {
+	var view struct {
+		Reviews []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"reviews"`
+	}
+	if err := g.run(ctx, &view, "pr", "view", strconv.Itoa(number), "--repo", repo.RepoKey(), "--json", "reviews"); err != nil {
+		return nil, err
+	}
+	var reviews []Review
+	for _, r := range view.Reviews {
+		var state ReviewState
+		switch r.State {
+		case "APPROVED":
+			state = ReviewApproved
+		case "CHANGES_REQUESTED":
+			state = ReviewChangesRequested
+		case "COMMENTED":
+			state = ReviewCommented
+		default: // PENDING, DISMISSED
+			continue
+		}
+		reviews = append(reviews, Review{Author: r.Author.Login, State: state})
+	}
+	return reviews, nil
+}
+
+func (g *GitHub) Checks(ctx context.Context, repo *domain.Repository, number int) ([]Check, error) {
+	var checks []struct {
+		Name   string `json:"name"`
+		Bucket string `json:"bucket"`
+		Link   string `json:"link"`
+	}
+	if err := g.run(ctx, &checks, "pr", "checks", strconv.Itoa(number), "--repo", repo.RepoKey(), "--json", "name,bucket,link"); err != nil {
+		if strings.Contains(err.Error(), "no checks reported") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	result := make([]Check, len(checks))
+	for i, c := range checks {
+		state := CheckPending
+		switch c.Bucket {
+		case "pass":
+			state = CheckSuccess
+		case "fail", "cancel":
+			state = CheckFailure
+		case "skipping":
+			state = CheckSkipped
+		}
+		result[i] = Check{Name: c.Name, State: state, URL: c.Link}
+	}
+	return result, nil
+}
+
+// run executes gh and decodes its JSON output into out. gh exits non-zero when
+// checks fail or are pending, so output that decodes is accepted regardless.
+func (g *GitHub) run(ctx context.Context, out any, args ...string) error {
+	cmd := exec.CommandContext(ctx, g.path, args...)
+	if env := g.Env(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if len(bytes.TrimSpace(stdout)) > 0 {
+		if jsonErr := json.Unmarshal(stdout, out); jsonErr == nil {
+			return nil
+		} else if err == nil {
+			return fmt.Errorf("gh %s: decode output: %w", args[0], jsonErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("gh %s %s: %w: %s", args[0], args[1], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
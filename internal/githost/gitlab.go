@@ -0,0 +1,162 @@
+package githost
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// GitLab talks to the GitLab REST API. Pull requests are merge requests and
+// checks are the jobs of the merge request's latest pipeline.
+type GitLab struct {
+	baseURL string
+	api     apiClient
+}
+
+// NewGitLab creates a GitLab host for baseURL (e.g. https://gitlab.com)
+// authenticating with a personal or project access token.
+func NewGitLab(baseURL, token string) *GitLab {
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &GitLab{baseURL: baseURL, api: newAPIClient(baseURL+"/api/v4", "PRIVATE-TOKEN", token)}
+}
+
+func (g *GitLab) Name() string { return HostGitLab }
+
+func (g *GitLab) Env() []string {
+	if g.baseURL == "https://gitlab.com" {
+		return nil
+	}
+	return []string{"GITLAB_HOST=" + g.baseURL}
+}
+
+func (g *GitLab) CloneURL(repo *domain.Repository) string {
+	return fmt.Sprintf("%s/%s.git", g.baseURL, repo.RepoKey())
+}
+
+type glMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	WebURL       string `json:"web_url"`
+	SourceBranch string `json:"source_branch"`
+	State        string `json:"state"` // opened, closed, locked, merged
+	Draft        bool   `json:"draft"`
+	Author       struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m glMergeRequest) normalize() PullRequest {
+	state := m.State
+	if state == "opened" || state == "locked" {
+		state = "open"
+	}
+	return PullRequest{
+		Number: m.IID,
+		Title:  m.Title,
+		URL:    m.WebURL,
+		Branch: m.SourceBranch,
+		Author: m.Author.Username,
+		State:  state,
+		Draft:  m.Draft,
+
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// project returns the API path of repo's project.
+func (g *GitLab) project(repo *domain.Repository) string {
+	return "/projects/" + url.PathEscape(repo.RepoKey())
+}
+
+func (g *GitLab) ListPullRequests(ctx context.Context, repo *domain.Repository, limit int) ([]PullRequest, error) {
+	var mrs []glMergeRequest
+	path := fmt.Sprintf("%s/merge_requests?state=opened&order_by=created_at&sort=desc&per_page=%d", g.project(repo), limit)
+	if err := g.api.get(ctx, path, &mrs); err != nil {
+		return nil, err
+	}
+	result := make([]PullRequest, len(mrs))
+	for i, m := range mrs {
+		result[i] = m.normalize()
+	}
+	return result, nil
+}
+
+func (g *GitLab) FindPullRequest(ctx context.Context, repo *domain.Repository, branch string) (*PullRequest, error) {
+	var mrs []glMergeRequest
+	path := fmt.Sprintf("%s/merge_requests?source_branch=%s&order_by=created_at&sort=desc&per_page=1", g.project(repo), url.QueryEscape(branch))
+	if err := g.api.get(ctx, path, &mrs); err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	pr := mrs[0].normalize()
+	return &pr, nil
+}
+
+// Reviews returns the approvals of a merge request; GitLab has no other
+// review states.
+func (g *GitLab) Reviews(ctx context.Context, repo *domain.Repository, number int) ([]Review, error) {
+	var approvals struct {
+		ApprovedBy []struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+		} `json:"approved_by"`
+	}
+	if err := g.api.get(ctx, fmt.Sprintf("%s/merge_requests/%d/approvals", g.project(repo), number), &approvals); err != nil {
+		return nil, err
+	}
+	reviews := make([]Review, len(approvals.ApprovedBy))
+	for i, a := range approvals.ApprovedBy {
+		reviews[i] = Review{Author: a.User.Username, State: ReviewApproved}
+	}
+	return reviews, nil
+}
+
+func (g *GitLab) Checks(ctx context.Context, repo *domain.Repository, number int) ([]Check, error) {
+	var pipelines []struct {
+		ID int `json:"id"`
+	}
+	if err := g.api.get(ctx, fmt.Sprintf("%s/merge_requests/%d/pipelines?per_page=1", g.project(repo), number), &pipelines); err != nil {
+		return nil, err
+	}
+	if len(pipelines) == 0 {
+		return nil, nil
+	}
+
+	var jobs []struct {
+		Name         string `json:"name"`
+		Status       string `json:"status"`
+		WebURL       string `json:"web_url"`
+		AllowFailure bool   `json:"allow_failure"`
+	}
+	if err := g.api.get(ctx, fmt.Sprintf("%s/pipelines/%d/jobs?per_page=100", g.project(repo), pipelines[0].ID), &jobs); err != nil {
+		return nil, err
+	}
+	checks := make([]Check, len(jobs))
+	for i, j := range jobs {
+		state := CheckPending
+		switch j.Status {
+		case "success":
+			state = CheckSuccess
+		case "failed", "canceled":
+			state = CheckFailure
+			if j.AllowFailure {
+				state = CheckSkipped
+			}
+		case "skipped", "manual":
+			state = CheckSkipped
+		}
+		checks[i] = Check{Name: j.Name, State: state, URL: j.WebURL}
+	}
+	return checks, nil
+}
@@ -0,0 +1,55 @@
+package githost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiClient makes authenticated JSON requests to a host's REST API.
+type apiClient struct {
+	baseURL string // e.g. https://gitlab.example.com/api/v4
+	header  string // authentication header name
+	token   string // header value; empty sends no header
+	client  *http.Client
+}
+
+func newAPIClient(baseURL, header, token string) apiClient {
+	return apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// get fetches baseURL+path and decodes the JSON response into out.
+func (c apiClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set(c.header, c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("GET %s: decode response: %w", path, err)
+	}
+	return nil
+}
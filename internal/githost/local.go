@@ -0,0 +1,223 @@
+package githost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// localStateFile holds the pull requests of a local bare repository.
+const localStateFile = "pulls.json"
+
+// Local is a stand-in for a hosting service backed by bare repositories at
+// <Root>/<owner>/<name>.git, for tests, offline replays and air-gapped setups.
+// Every branch other than the default branch is an open pull request; reviews
+// and checks are set with AddReview and SetCheck. The pull requests are kept
+// in pulls.json inside the bare repository.
+type Local struct {
+	root string
+	mu   sync.Mutex
+}
+
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+func (l *Local) Name() string { return HostLocal }
+
+func (l *Local) Env() []string { return nil }
+
+// Dir returns the bare repository of repo.
+func (l *Local) Dir(repo *domain.Repository) string {
+	return filepath.Join(l.root, repo.Owner, repo.Name+".git")
+}
+
+// CloneURL returns the bare repository, which checkouts use as origin.
+func (l *Local) CloneURL(repo *domain.Repository) string {
+	return l.Dir(repo)
+}
+
+type localPull struct {
+	PullRequest
+	Reviews []Review `json:"reviews,omitempty"`
+	Checks  []Check  `json:"checks,omitempty"`
+}
+
+func (l *Local) ListPullRequests(ctx context.Context, repo *domain.Repository, limit int) ([]PullRequest, error) {
+	var result []PullRequest
+	err := l.update(ctx, repo, func(pulls []*localPull) error {
+		for i := len(pulls) - 1; i >= 0 && len(result) < limit; i-- {
+			if pulls[i].State == "open" {
+				result = append(result, pulls[i].PullRequest)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (l *Local) FindPullRequest(ctx context.Context, repo *domain.Repository, branch string) (*PullRequest, error) {
+	var found *PullRequest
+	err := l.update(ctx, repo, func(pulls []*localPull) error {
+		for i := len(pulls) - 1; i >= 0; i-- {
+			if pulls[i].Branch == branch {
+				pr := pulls[i].PullRequest
+				found = &pr
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (l *Local) Reviews(ctx context.Context, repo *domain.Repository, number int) ([]Review, error) {
+	var reviews []Review
+	err := l.updatePull(ctx, repo, number, func(p *localPull) {
+		reviews = p.Reviews
+	})
+	return reviews, err
+}
+
+func (l *Local) Checks(ctx context.Context, repo *domain.Repository, number int) ([]Check, error) {
+	var checks []Check
+	err := l.updatePull(ctx, repo, number, func(p *localPull) {
+		checks = p.Checks
+	})
+	return checks, err
+}
+
+// AddReview records a review of a pull request.
+func (l *Local) AddReview(ctx context.Context, repo *domain.Repository, number int, review Review) error {
+	return l.updatePull(ctx, repo, number, func(p *localPull) {
+		p.Reviews = append(p.Reviews, review)
+	})
+}
+
+// SetCheck adds or replaces the check with the same name on a pull request.
+func (l *Local) SetCheck(ctx context.Context, repo *domain.Repository, number int, check Check) error {
+	return l.updatePull(ctx, repo, number, func(p *localPull) {
+		for i := range p.Checks {
+			if p.Checks[i].Name == check.Name {
+				p.Checks[i] = check
+				return
+			}
+		}
+		p.Checks = append(p.Checks, check)
+	})
+}
+
+func (l *Local) updatePull(ctx context.Context, repo *domain.Repository, number int, fn func(*localPull)) error {
+	return l.update(ctx, repo, func(pulls []*localPull) error {
+		for _, p := range pulls {
+			if p.Number == number {
+				fn(p)
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: pull request #%d not found", repo.RepoKey(), number)
+	})
+}
+
+// update loads the pull requests, opens one for each new branch, closes those
+// whose branch is gone, runs fn and saves the result.
+func (l *Local) update(ctx context.Context, repo *domain.Repository, fn func([]*localPull) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dir := l.Dir(repo)
+	statePath := filepath.Join(dir, localStateFile)
+	var pulls []*localPull
+	data, err := os.ReadFile(statePath)
+	if err == nil {
+		if err := json.Unmarshal(data, &pulls); err != nil {
+			return fmt.Errorf("parse %s: %w", statePath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	branches, err := l.branches(ctx, dir)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, p := range pulls {
+		if p.State == "open" {
+			if _, ok := branches[p.Branch]; !ok {
+				p.State = "closed"
+			}
+		}
+		known[p.Branch] = true
+	}
+	for _, b := range sortedKeys(branches) {
+		if known[b] || b == repo.DefaultBranch {
+			continue
+		}
+		pulls = append(pulls, &localPull{PullRequest: PullRequest{
+			Number: len(pulls) + 1,
+			Title:  branches[b].subject,
+			URL:    fmt.Sprintf("file://%s/pull/%d", dir, len(pulls)+1),
+			Branch: b,
+			Author: branches[b].author,
+			State:  "open",
+
+			CreatedAt: time.Now(),
+		}})
+	}
+
+	if err := fn(pulls); err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(pulls, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath, data, 0o644)
+}
+
+type localBranch struct {
+	subject string // of the head commit, used as the pull request title
+	author  string
+}
+
+func (l *Local) branches(ctx context.Context, dir string) (map[string]localBranch, error) {
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", dir, "for-each-ref",
+		"--format=%(refname:short)%09%(authorname)%09%(contents:subject)", "refs/heads")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list branches of %s: %w: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	branches := make(map[string]localBranch)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 {
+			branches[fields[0]] = localBranch{author: fields[1], subject: fields[2]}
+		}
+	}
+	return branches, nil
+}
+
+func sortedKeys(m map[string]localBranch) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,129 @@
+package githost
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// newLocalRepo creates the bare repository of example/repo under a new Local
+// root, with a single commit on main.
+func newLocalRepo(t *testing.T) (*Local, *domain.Repository) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_AUTHOR_NAME", "Alice")
+	t.Setenv("GIT_AUTHOR_EMAIL", "alice@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Alice")
+	t.Setenv("GIT_COMMITTER_EMAIL", "alice@example.com")
+
+	local := NewLocal(t.TempDir())
+	repo := &domain.Repository{Owner: "example", Name: "repo", DefaultBranch: "main"}
+	work := filepath.Join(t.TempDir(), "work")
+	git(t, "", "init", "--quiet", "--initial-branch=main", work)
+	git(t, work, "commit", "--quiet", "--allow-empty", "-m", "Initial commit")
+	git(t, "", "clone", "--quiet", "--bare", work, local.Dir(repo))
+	return local, repo
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func TestLocalPullRequests(t *testing.T) {
+	ctx := context.Background()
+	local, repo := newLocalRepo(t)
+	bare := local.Dir(repo)
+
+	if prs, err := local.ListPullRequests(ctx, repo, 10); err != nil || len(prs) != 0 {
+		t.Fatalf("ListPullRequests() = %+v, %v; want none for the default branch", prs, err)
+	}
+
+	git(t, "", "--git-dir", bare, "branch", "fix-typo", "main")
+	git(t, "", "--git-dir", bare, "branch", "add-tests", "main")
+	prs, err := local.ListPullRequests(ctx, repo, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 || prs[0].Branch != "fix-typo" || prs[1].Branch != "add-tests" {
+		t.Fatalf("ListPullRequests() = %+v; want fix-typo then add-tests, newest first", prs)
+	}
+
+	pr, err := local.FindPullRequest(ctx, repo, "fix-typo")
+	if err != nil || pr == nil {
+		t.Fatalf("FindPullRequest(fix-typo) = %+v, %v", pr, err)
+	}
+	if pr.Number != 2 || pr.State != "open" || pr.Title != "Initial commit" || pr.Author != "Alice" || pr.CreatedAt.IsZero() {
+		t.Errorf("FindPullRequest(fix-typo) = %+v", pr)
+	}
+	if pr, err := local.FindPullRequest(ctx, repo, "unknown"); err != nil || pr != nil {
+		t.Errorf("FindPullRequest(unknown) = %+v, %v; want nil", pr, err)
+	}
+
+	// Deleting the branch closes the pull request; recreating it opens a new one
+	git(t, "", "--git-dir", bare, "branch", "-D", "fix-typo")
+	if pr, _ := local.FindPullRequest(ctx, repo, "fix-typo"); pr == nil || pr.State != "closed" {
+		t.Fatalf("FindPullRequest(fix-typo) after deletion = %+v; want closed", pr)
+	}
+	if prs, _ := local.ListPullRequests(ctx, repo, 10); len(prs) != 1 || prs[0].Branch != "add-tests" {
+		t.Errorf("ListPullRequests() after deletion = %+v; want add-tests only", prs)
+	}
+}
+
+func TestLocalReviewsAndChecks(t *testing.T) {
+	ctx := context.Background()
+	local, repo := newLocalRepo(t)
+	git(t, "", "--git-dir", local.Dir(repo), "branch", "feature", "main")
+	pr, err := local.FindPullRequest(ctx, repo, "feature")
+	if err != nil || pr == nil {
+		t.Fatalf("FindPullRequest(feature) = %+v, %v", pr, err)
+	}
+
+	reviews := []Review{
+		{Author: "bob", State: ReviewChangesRequested},
+		{Author: "bob", State: ReviewApproved},
+	}
+	for _, r := range reviews {
+		if err := local.AddReview(ctx, repo, pr.Number, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := local.Reviews(ctx, repo, pr.Number); err != nil || !reflect.DeepEqual(got, reviews) {
+		t.Errorf("Reviews() = %+v, %v; want %+v", got, err, reviews)
+	}
+
+	for _, c := range []Check{
+		{Name: "test", State: CheckPending},
+		{Name: "lint", State: CheckSuccess},
+		{Name: "test", State: CheckFailure, URL: "https://ci.example.com/1"},
+	} {
+		if err := local.SetCheck(ctx, repo, pr.Number, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Check{
+		{Name: "test", State: CheckFailure, URL: "https://ci.example.com/1"},
+		{Name: "lint", State: CheckSuccess},
+	}
+	if got, err := local.Checks(ctx, repo, pr.Number); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Checks() = %+v, %v; want %+v", got, err, want)
+	}
+
+	// The state survives a new Local on the same root
+	reopened := NewLocal(filepath.Dir(filepath.Dir(local.Dir(repo))))
+	if got, err := reopened.Checks(ctx, repo, pr.Number); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Checks() after reopening = %+v, %v; want %+v", got, err, want)
+	}
+
+	if err := local.AddReview(ctx, repo, 99, reviews[0]); err == nil {
+		t.Error("AddReview(#99): want an error for an unknown pull request")
+	}
+}
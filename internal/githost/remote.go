@@ -0,0 +1,54 @@
+package githost
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Remote is a parsed git remote URL.
+type Remote struct {
+	Host  string // hostname, empty for local paths
+	Owner string // may contain "/" (GitLab subgroups)
+	Name  string
+}
+
+// Key returns owner/name.
+func (r Remote) Key() string {
+	return r.Owner + "/" + r.Name
+}
+
+// ParseRemote parses an HTTPS, SSH (URL or scp-like) or file remote URL. The
+// last path element is the name and everything before it the owner, so
+// GitLab subgroups (group/subgroup/project) are kept in the owner.
+func ParseRemote(raw string) (Remote, error) {
+	raw = strings.TrimSpace(raw)
+	var host, path string
+	switch {
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return Remote{}, fmt.Errorf("parse remote %s: %w", raw, err)
+		}
+		host, path = u.Hostname(), u.Path
+	case strings.Contains(raw, ":") && !strings.HasPrefix(raw, "/"):
+		// scp-like: [user@]host:path
+		var userHost string
+		userHost, path, _ = strings.Cut(raw, ":")
+		host = userHost[strings.LastIndex(userHost, "@")+1:]
+	default:
+		path = raw
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return Remote{}, fmt.Errorf("cannot parse remote %s: want <host>/<owner>/<name>", raw)
+	}
+	owner := path[:i]
+	if host == "" {
+		// Local path: the owner is the parent directory only
+		owner = owner[strings.LastIndex(owner, "/")+1:]
+	}
+	return Remote{Host: strings.ToLower(host), Owner: owner, Name: path[i+1:]}, nil
+}
@@ -0,0 +1,102 @@
+// Package identity maps Slack users to the git identity their tasks' commits
+// are attributed to.
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// ProfileSource looks up a Slack user's profile. It is implemented by
+// *slackclient.Client (requires the users:read.email scope).
+type ProfileSource interface {
+	UserProfile(ctx context.Context, userID string) (name, email string, err error)
+}
+
+// Options configures a Directory.
+type Options struct {
+	// Path is a JSON file mapping Slack user IDs to git identities:
+	// {"U0123ABCD": {"name": "Jane Doe", "email": "jane@example.com"}}.
+	// Empty for none.
+	Path string
+
+	// Profiles looks up users missing from the file; nil disables.
+	Profiles ProfileSource
+}
+
+// Directory resolves the git identity of Slack users: from the mapping file
+// first, then from their Slack profile. Profiles are cached for the life of
+// the process.
+type Directory struct {
+	mapped   map[string]domain.GitIdentity // key: Slack user ID
+	profiles ProfileSource
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	cached map[string]domain.GitIdentity
+}
+
+func New(opts Options, logger *slog.Logger) (*Directory, error) {
+	d := &Directory{
+		mapped:   make(map[string]domain.GitIdentity),
+		profiles: opts.Profiles,
+		logger:   logger,
+		cached:   make(map[string]domain.GitIdentity),
+	}
+	if opts.Path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &d.mapped); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", opts.Path, err)
+	}
+	for user, id := range d.mapped {
+		if id.Name == "" || !strings.Contains(id.Email, "@") {
+			return nil, fmt.Errorf("%s: %s: name and email are required", opts.Path, user)
+		}
+	}
+	return d, nil
+}
+
+// Lookup returns the git identity of a Slack user, or false if it is unknown.
+func (d *Directory) Lookup(ctx context.Context, userID string) (domain.GitIdentity, bool) {
+	if id, ok := d.mapped[userID]; ok {
+		return id, true
+	}
+	if d.profiles == nil || userID == "" {
+		return domain.GitIdentity{}, false
+	}
+
+	d.mu.Lock()
+	id, ok := d.cached[userID]
+	d.mu.Unlock()
+	if ok {
+		return id, true
+	}
+
+	name, email, err := d.profiles.UserProfile(ctx, userID)
+	if err != nil {
+		d.logger.Warn("failed to look up slack profile", "user", userID, "error", err)
+		return domain.GitIdentity{}, false
+	}
+	if name == "" || email == "" {
+		d.logger.Info("slack profile has no email", "user", userID)
+		return domain.GitIdentity{}, false
+	}
+	id = domain.GitIdentity{Name: name, Email: email}
+	d.mu.Lock()
+	d.cached[userID] = id
+	d.mu.Unlock()
+	return id, true
+}
@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome is how a run ended.
+type Outcome string
+
+const (
+	OutcomeSuccess        Outcome = "success"
+	OutcomeError          Outcome = "error"
+	OutcomeCancelled      Outcome = "cancelled"
+	OutcomeBudgetExceeded Outcome = "budget_exceeded"
+	OutcomeTimedOut       Outcome = "timed_out"
+	OutcomeInterrupted    Outcome = "interrupted"
+)
+
+// Entry is a single run recorded in the ledger.
+type Entry struct {
+	TaskID     string    `json:"task_id"`
+	StartedAt  time.Time `json:"started_at"`
+	User       string    `json:"user"`
+	Channel    string    `json:"channel"`
+	ThreadTS   string    `json:"thread_ts"`
+	Repository string    `json:"repository"`
+	Related    []string  `json:"related,omitempty"` // other repositories of a multi-repository task
+	Mode       string    `json:"mode"`
+	Model      string    `json:"model,omitempty"`
+	BaseSHA    string    `json:"base_sha,omitempty"`
+	PromptHash string    `json:"prompt_hash"`
+	DurationMS int64     `json:"duration_ms"`
+	Turns      int       `json:"turns"`
+	Cost       float64   `json:"cost_usd"`
+	Outcome    Outcome   `json:"outcome"`
+	PRURL      string    `json:"pr_url,omitempty"`
+
+	RelatedPRURLs []string `json:"related_pr_urls,omitempty"` // pull requests opened in the related repositories
+}
+
+// HashPrompt returns a short hash identifying a prompt without storing its text.
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Ledger is an append-only JSON Lines file of run entries.
+type Ledger struct {
+	mu   sync.Mutex
+	path string
+}
+
+func New(path string) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create ledger dir: %w", err)
+	}
+	return &Ledger{path: path}, nil
+}
+
+// Append writes an entry to the end of the ledger.
+func (l *Ledger) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Since returns all entries started at or after the given time.
+func (l *Ledger) Since(t time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // skip corrupt lines
+		}
+		if !e.StartedAt.Before(t) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Total is an aggregated cost/run count for a single key.
+type Total struct {
+	Key  string
+	Runs int
+	Cost float64
+}
+
+// Report holds totals grouped by user, repository and day.
+type Report struct {
+	Runs   int
+	Cost   float64
+	ByUser []Total
+	ByRepo []Total
+	ByDay  []Total
+}
+
+// Summarize aggregates entries into a report. Users and repositories are
+// sorted by cost (descending), days chronologically.
+func Summarize(entries []Entry) Report {
+	users := make(map[string]*Total)
+	repos := make(map[string]*Total)
+	days := make(map[string]*Total)
+
+	add := func(m map[string]*Total, key string, e Entry) {
+		t, ok := m[key]
+		if !ok {
+			t = &Total{Key: key}
+			m[key] = t
+		}
+		t.Runs++
+		t.Cost += e.Cost
+	}
+
+	var r Report
+	for _, e := range entries {
+		r.Runs++
+		r.Cost += e.Cost
+		add(users, e.User, e)
+		add(repos, e.Repository, e)
+		add(days, e.StartedAt.Local().Format("2006-01-02"), e)
+	}
+
+	r.ByUser = sortedTotals(users, byCost)
+	r.ByRepo = sortedTotals(repos, byCost)
+	r.ByDay = sortedTotals(days, byKey)
+	return r
+}
+
+func byCost(a, b Total) bool {
+	if a.Cost != b.Cost {
+		return a.Cost > b.Cost
+	}
+	return a.Key < b.Key
+}
+
+func byKey(a, b Total) bool {
+	return a.Key < b.Key
+}
+
+func sortedTotals(m map[string]*Total, less func(a, b Total) bool) []Total {
+	totals := make([]Total, 0, len(m))
+	for _, t := range m {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return less(totals[i], totals[j]) })
+	return totals
+}
+
+// WriteCSV writes entries as CSV with a header row.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	header := []string{"task_id", "started_at", "user", "channel", "thread_ts", "repository", "mode",
+		"prompt_hash", "duration_ms", "turns", "cost_usd", "outcome", "pr_url", "model", "base_sha", "related", "related_pr_urls"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.TaskID,
+			e.StartedAt.Format(time.RFC3339),
+			e.User,
+			e.Channel,
+			e.ThreadTS,
+			e.Repository,
+			e.Mode,
+			e.PromptHash,
+			strconv.FormatInt(e.DurationMS, 10),
+			strconv.Itoa(e.Turns),
+			strconv.FormatFloat(e.Cost, 'f', 4, 64),
+			string(e.Outcome),
+			e.PRURL,
+			e.Model,
+			e.BaseSHA,
+			strings.Join(e.Related, " "),
+			strings.Join(e.RelatedPRURLs, " "),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
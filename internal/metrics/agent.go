@@ -0,0 +1,36 @@
+package metrics
+
+// Default is the registry exposed on /metrics.
+var Default = NewRegistry()
+
+var (
+	EventsReceived = Default.NewCounter("slack_claude_events_received_total",
+		"Slack events received via Socket Mode.", "type")
+
+	RunsStarted = Default.NewCounter("slack_claude_runs_started_total",
+		"Claude runs started.", "repository", "mode")
+	RunsCompleted = Default.NewCounter("slack_claude_runs_completed_total",
+		"Claude runs finished, by outcome.", "repository", "outcome")
+	RunsFailed = Default.NewCounter("slack_claude_runs_failed_total",
+		"Claude runs that failed with an error or timed out (stops and interruptions are not failures).", "repository")
+	RunRetries = Default.NewCounter("slack_claude_run_retries_total",
+		"Runs retried after a transient failure, by failure kind.", "repository", "kind")
+	RunsInProgress = Default.NewGauge("slack_claude_runs_in_progress",
+		"Claude runs currently in flight (including those waiting for a scheduler slot).")
+
+	RunDuration = Default.NewHistogram("slack_claude_run_duration_seconds",
+		"Wall-clock duration of Claude runs.",
+		[]float64{10, 30, 60, 120, 300, 600, 900, 1800, 3600}, "repository")
+	RunCost = Default.NewHistogram("slack_claude_run_cost_usd",
+		"Cost of Claude runs in USD.",
+		[]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10}, "repository")
+	CostTotal = Default.NewCounter("slack_claude_cost_usd_total",
+		"Total cost of Claude runs in USD.", "repository")
+
+	SemaphoreWait = Default.NewHistogram("slack_claude_semaphore_wait_seconds",
+		"Time spent waiting for a scheduler slot before starting claude.",
+		[]float64{0.01, 0.1, 1, 5, 15, 30, 60, 300, 900}, "repository")
+
+	SlackAPIErrors = Default.NewCounter("slack_claude_slack_api_errors_total",
+		"Failed Slack Web API calls.", "method")
+)
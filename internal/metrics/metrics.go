@@ -0,0 +1,245 @@
+// Package metrics implements the small subset of Prometheus metric types the
+// agent needs (labelled counters, gauges and histograms) and renders them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format.
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// desc is the name, help text and label names shared by all metric types.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) header(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, typ)
+}
+
+// key joins label values into a map key.
+func key(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// labelValueEscaper escapes a label value for the text format, which only
+// knows \\, \" and \n (Go's %q would also emit \t, \x.. and \u....).
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (d desc) labelString(values []string, extra ...string) string {
+	var pairs []string
+	for i, name := range d.labels {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(v)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], labelValueEscaper.Replace(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// valueVec stores one float per label combination. Used by counters and gauges.
+type valueVec struct {
+	desc
+	typ    string
+	mu     sync.Mutex
+	values map[string]float64
+	labels map[string][]string
+}
+
+func newValueVec(r *Registry, typ, name, help string, labels ...string) *valueVec {
+	v := &valueVec{
+		desc:   desc{name: name, help: help, labels: labels},
+		typ:    typ,
+		values: make(map[string]float64),
+		labels: make(map[string][]string),
+	}
+	r.register(v)
+	return v
+}
+
+func (v *valueVec) add(delta float64, labels []string) {
+	k := key(labels)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] += delta
+	v.labels[k] = labels
+}
+
+func (v *valueVec) set(val float64, labels []string) {
+	k := key(labels)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] = val
+	v.labels[k] = labels
+}
+
+func (v *valueVec) write(w io.Writer) {
+	v.header(w, v.typ)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 && len(v.desc.labels) == 0 {
+		fmt.Fprintf(w, "%s 0\n", v.name)
+		return
+	}
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelString(v.labels[k]), formatFloat(v.values[k]))
+	}
+}
+
+// Counter is a monotonically increasing value, optionally partitioned by labels.
+type Counter struct{ vec *valueVec }
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{vec: newValueVec(r, "counter", name, help, labels...)}
+}
+
+// Inc increments the counter for the given label values.
+func (c *Counter) Inc(labels ...string) {
+	c.vec.add(1, labels)
+}
+
+// Add adds delta (must be >= 0) for the given label values.
+func (c *Counter) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		return
+	}
+	c.vec.add(delta, labels)
+}
+
+// Gauge is a value that can go up and down, optionally partitioned by labels.
+type Gauge struct{ vec *valueVec }
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{vec: newValueVec(r, "gauge", name, help, labels...)}
+}
+
+func (g *Gauge) Set(val float64, labels ...string) {
+	g.vec.set(val, labels)
+}
+
+func (g *Gauge) Add(delta float64, labels ...string) {
+	g.vec.add(delta, labels)
+}
+
+// Histogram counts observations into cumulative buckets, optionally partitioned by labels.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a value for the given label values.
+func (h *Histogram) Observe(val float64, labels ...string) {
+	k := key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	for i, upper := range h.buckets {
+		if val <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += val
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(s.labels, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelString(s.labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelString(s.labels), s.count)
+	}
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("runs_total", "Runs.", "repository")
+	c.Inc("org/a\\b\"c\nd\té")
+
+	var out strings.Builder
+	r.WritePrometheus(&out)
+	want := "runs_total{repository=\"org/a\\\\b\\\"c\\nd\té\"} 1\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package runstore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recording is a recorded stream-json output that can be replayed.
+type Recording struct {
+	open    func() (io.ReadCloser, error)
+	Offsets []time.Duration // when each line arrived, from the start; nil if unknown
+}
+
+// LoadRecording locates a recording for replay. path is either a run directory
+// written by Store or a stream-json file (decompressed if it ends in ".gz").
+func LoadRecording(path string) (*Recording, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return &Recording{open: func() (io.ReadCloser, error) { return openFile(path) }}, nil
+	}
+
+	streamPath := filepath.Join(path, streamFile)
+	r, err := openOutputFile(streamPath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", streamFile, err)
+	}
+	r.Close()
+
+	offsets, err := readOffsets(filepath.Join(path, timingFile))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", timingFile, err)
+	}
+	return &Recording{
+		open:    func() (io.ReadCloser, error) { return openOutputFile(streamPath) },
+		Offsets: offsets,
+	}, nil
+}
+
+// Open opens the recorded stream from the beginning.
+func (r *Recording) Open() (io.ReadCloser, error) {
+	return r.open()
+}
+
+// readOffsets reads the timing file; a missing file yields nil offsets.
+func readOffsets(path string) ([]time.Duration, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var offsets []time.Duration
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ms, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, time.Duration(ms)*time.Millisecond)
+	}
+	return offsets, scanner.Err()
+}
@@ -0,0 +1,422 @@
+package runstore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+)
+
+// Files inside a run directory. Output files are gzip-compressed with a ".gz"
+// suffix; plain files written by older versions are still readable.
+const (
+	metaFile   = "meta.json"     // ledger.Entry written when the run finishes
+	streamFile = "stream.jsonl"  // raw stream-json output of claude
+	stderrFile = "stderr.log"    // stderr of claude
+	diffFile   = "diff.patch"    // changes made in the workspace during the run
+	timingFile = "stream.timing" // offset in ms of each stream line from the start, for replay
+)
+
+// ErrNotFound is returned for unknown task IDs.
+var ErrNotFound = errors.New("run not found")
+
+var taskIDRe = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Options configures a Store. Zero values mean "unlimited".
+type Options struct {
+	MaxBytes  int64         // cap on each output file (uncompressed); the rest is dropped
+	Retention time.Duration // runs older than this are deleted by Prune
+}
+
+// Store keeps one directory per run, named after the task ID.
+type Store struct {
+	dir  string
+	opts Options
+}
+
+func New(dir string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create run dir: %w", err)
+	}
+	return &Store{dir: dir, opts: opts}, nil
+}
+
+// Run is a run being recorded.
+type Run struct {
+	dir           string
+	maxBytes      int64
+	stream        *cappedWriter
+	stderr        *cappedWriter
+	timing        *timedWriter
+	diffTruncated bool
+}
+
+// Create starts recording a run.
+func (s *Store) Create(taskID string) (*Run, error) {
+	if !taskIDRe.MatchString(taskID) {
+		return nil, fmt.Errorf("invalid task id %q", taskID)
+	}
+	dir := filepath.Join(s.dir, taskID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	stream, err := newCappedWriter(filepath.Join(dir, streamFile+".gz"), s.opts.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := newCappedWriter(filepath.Join(dir, stderrFile+".gz"), s.opts.MaxBytes)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	timing, err := newTimedWriter(filepath.Join(dir, timingFile), stream)
+	if err != nil {
+		stream.Close()
+		stderr.Close()
+		return nil, err
+	}
+	return &Run{dir: dir, maxBytes: s.opts.MaxBytes, stream: stream, stderr: stderr, timing: timing}, nil
+}
+
+// Stream is where the raw stream-json output is written. The time each line
+// arrives is recorded too, so the run can be replayed with its original pacing.
+func (r *Run) Stream() io.Writer {
+	return r.timing
+}
+
+// Stderr is where claude's stderr is written.
+func (r *Run) Stderr() io.Writer {
+	return r.stderr
+}
+
+// WriteDiff saves the changes made during the run, compressed and capped
+// like the other output files.
+func (r *Run) WriteDiff(diff string) error {
+	if diff == "" {
+		return nil
+	}
+	w, err := newCappedWriter(filepath.Join(r.dir, diffFile+".gz"), r.maxBytes)
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, diff)
+	r.diffTruncated = w.truncated
+	return w.Close()
+}
+
+// Finish closes the output files and writes the run's metadata.
+func (r *Run) Finish(meta ledger.Entry) error {
+	streamErr := r.stream.Close()
+	stderrErr := r.stderr.Close()
+	if err := r.timing.Close(); err != nil && streamErr == nil {
+		streamErr = err
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, metaFile), data, 0o644); err != nil {
+		return err
+	}
+
+	if streamErr != nil {
+		return fmt.Errorf("write %s: %w", streamFile, streamErr)
+	}
+	if stderrErr != nil {
+		return fmt.Errorf("write %s: %w", stderrFile, stderrErr)
+	}
+	return nil
+}
+
+// Truncated reports whether any output exceeded the size cap.
+func (r *Run) Truncated() bool {
+	return r.stream.truncated || r.stderr.truncated || r.diffTruncated
+}
+
+// List returns the metadata of finished runs, newest first, at most limit (0 = all).
+func (s *Store) List(limit int) ([]ledger.Entry, error) {
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []ledger.Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		meta, err := s.Meta(d.Name())
+		if err != nil {
+			continue // still running or incomplete
+		}
+		runs = append(runs, meta)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// LastInThread returns the latest finished run in a Slack thread.
+func (s *Store) LastInThread(threadTS string) (ledger.Entry, error) {
+	runs, err := s.List(0)
+	if err != nil {
+		return ledger.Entry{}, err
+	}
+	for _, run := range runs {
+		if run.ThreadTS == threadTS {
+			return run, nil
+		}
+	}
+	return ledger.Entry{}, ErrNotFound
+}
+
+// Meta returns the metadata of a finished run.
+func (s *Store) Meta(taskID string) (ledger.Entry, error) {
+	var meta ledger.Entry
+	data, err := s.readFile(taskID, metaFile)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("parse %s: %w", metaFile, err)
+	}
+	return meta, nil
+}
+
+// OpenStream opens the raw stream-json output of a run.
+func (s *Store) OpenStream(taskID string) (io.ReadCloser, error) {
+	return s.openOutput(taskID, streamFile)
+}
+
+// OpenStderr opens claude's stderr of a run.
+func (s *Store) OpenStderr(taskID string) (io.ReadCloser, error) {
+	return s.openOutput(taskID, stderrFile)
+}
+
+// Diff returns the changes made during a run, or "" if there were none.
+func (s *Store) Diff(taskID string) (string, error) {
+	r, err := s.openOutput(taskID, diffFile)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// Prune deletes runs older than the retention period and returns how many were removed.
+func (s *Store) Prune() (int, error) {
+	if s.opts.Retention <= 0 {
+		return 0, nil
+	}
+
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-s.opts.Retention)
+	removed := 0
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		// meta.json is the last file written, so its mtime is when the run finished.
+		// Runs that never finished (e.g. the process crashed) fall back to the directory.
+		info, err := os.Stat(filepath.Join(s.dir, d.Name(), metaFile))
+		if os.IsNotExist(err) {
+			info, err = d.Info()
+		}
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, d.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// PruneLoop runs Prune now and then every interval until ctx is cancelled.
+func (s *Store) PruneLoop(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if s.opts.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Prune(); err != nil {
+			logger.Error("failed to prune runs", "error", err)
+		} else if n > 0 {
+			logger.Info("pruned old runs", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Store) readFile(taskID, name string) ([]byte, error) {
+	if !taskIDRe.MatchString(taskID) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, taskID, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// openOutput opens a compressed output file, falling back to the plain file.
+func (s *Store) openOutput(taskID, name string) (io.ReadCloser, error) {
+	if !taskIDRe.MatchString(taskID) {
+		return nil, ErrNotFound
+	}
+	return openOutputFile(filepath.Join(s.dir, taskID, name))
+}
+
+// openOutputFile opens path+".gz", falling back to the plain path.
+func openOutputFile(path string) (io.ReadCloser, error) {
+	r, err := openFile(path + ".gz")
+	if errors.Is(err, ErrNotFound) {
+		return openFile(path)
+	}
+	return r, err
+}
+
+// openFile opens a file, decompressing it if its name ends in ".gz".
+func openFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return f, err
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: gz, f: f}, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.f.Close()
+}
+
+// cappedWriter gzips output into a file and drops anything past max bytes.
+// Write never fails, so a full disk cannot break the run being recorded;
+// the first write error is reported by Close.
+type cappedWriter struct {
+	f         *os.File
+	gz        *gzip.Writer
+	max       int64
+	written   int64
+	truncated bool
+	err       error
+}
+
+func newCappedWriter(path string, max int64) (*cappedWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &cappedWriter{f: f, gz: gzip.NewWriter(f), max: max}, nil
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.err != nil || w.truncated {
+		return n, nil
+	}
+	if w.max > 0 && w.written+int64(len(p)) > w.max {
+		p = p[:w.max-w.written]
+		w.truncated = true
+	}
+	_, w.err = w.gz.Write(p)
+	w.written += int64(len(p))
+	return n, nil
+}
+
+func (w *cappedWriter) Close() error {
+	if w.truncated && w.err == nil {
+		_, w.err = fmt.Fprintf(w.gz, "\n[truncated after %d bytes]\n", w.written)
+	}
+	if err := w.gz.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	if err := w.f.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// timedWriter passes stream output through to w and records the offset of
+// every complete line that was not cut off by the size cap.
+type timedWriter struct {
+	w     *cappedWriter
+	f     *os.File
+	buf   *bufio.Writer
+	start time.Time
+}
+
+func newTimedWriter(path string, w *cappedWriter) (*timedWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &timedWriter{w: w, f: f, buf: bufio.NewWriter(f), start: time.Now()}, nil
+}
+
+func (t *timedWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if t.w.truncated {
+		return n, err
+	}
+	offset := time.Since(t.start).Milliseconds()
+	for i := bytes.Count(p, []byte("\n")); i > 0; i-- {
+		fmt.Fprintln(t.buf, offset)
+	}
+	return n, err
+}
+
+func (t *timedWriter) Close() error {
+	flushErr := t.buf.Flush()
+	if err := t.f.Close(); err != nil && flushErr == nil {
+		flushErr = err
+	}
+	return flushErr
+}
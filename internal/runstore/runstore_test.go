@@ -0,0 +1,71 @@
+package runstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+)
+
+func TestRunOutputsAreCappedAndCompressed(t *testing.T) {
+	store, err := New(t.TempDir(), Options{MaxBytes: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := store.Create("task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Stream().Write([]byte("{\"type\":\"system\"}\n"))
+	if err := run.WriteDiff("diff --git a/README.md b/README.md\n"); err != nil {
+		t.Fatal(err)
+	}
+	if !run.Truncated() {
+		t.Error("Truncated() = false, want true past MaxBytes")
+	}
+	if err := run.Finish(ledger.Entry{TaskID: "task-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.dir, "task-1", diffFile+".gz")); err != nil {
+		t.Errorf("compressed diff: %v", err)
+	}
+	diff, err := store.Diff("task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "diff --git a/REA\n[truncated after 16 bytes]\n"; diff != want {
+		t.Errorf("Diff() = %q, want %q", diff, want)
+	}
+}
+
+func TestDiffReadsPlainFilesAndMissingDiffs(t *testing.T) {
+	store, err := New(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(store.dir, "old-task"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plain := "diff --git a/go.mod b/go.mod\n"
+	if err := os.WriteFile(filepath.Join(store.dir, "old-task", diffFile), []byte(plain), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := store.Diff("old-task"); err != nil || diff != plain {
+		t.Errorf("Diff(old-task) = %q, %v; want the plain diff", diff, err)
+	}
+
+	run, err := store.Create("no-changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.WriteDiff("")
+	run.Finish(ledger.Entry{TaskID: "no-changes"})
+	if diff, err := store.Diff("no-changes"); err != nil || diff != "" {
+		t.Errorf("Diff(no-changes) = %q, %v; want empty", diff, err)
+	}
+	if diff, err := store.Diff("../etc"); err != nil || diff != "" {
+		t.Errorf("Diff(../etc) = %q, %v; want empty", diff, err)
+	}
+}
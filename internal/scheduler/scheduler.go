@@ -0,0 +1,291 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Limits caps concurrent runs. Zero PerRepo/PerUser means "unlimited".
+type Limits struct {
+	Global  int // max concurrent runs across all repositories and users
+	PerRepo int // max concurrent runs per checkout, shared by the targets of a monorepo
+	PerUser int // max concurrent runs per Slack user
+}
+
+// Request describes a run waiting for a slot.
+type Request struct {
+	User     string
+	Repo     string
+	Related  []string // other repositories the run changes; each also takes a PerRepo slot
+	Priority int      // higher priorities are scheduled first
+}
+
+// checkouts returns the checkouts (domain.RepoKeyOf) of all repositories of
+// the request, primary first, each once.
+func (r Request) checkouts() []string {
+	var checkouts []string
+	seen := make(map[string]bool)
+	for _, repo := range append([]string{r.Repo}, r.Related...) {
+		if c := domain.RepoKeyOf(repo); !seen[c] {
+			seen[c] = true
+			checkouts = append(checkouts, c)
+		}
+	}
+	return checkouts
+}
+
+// Position is a waiting request's place in the queue.
+type Position struct {
+	Ahead int           // requests that will be scheduled before this one
+	ETA   time.Duration // estimated wait; 0 if there is no run history yet
+}
+
+// Stats is a snapshot of the scheduler state.
+type Stats struct {
+	Limits  Limits
+	Running int
+	Queued  int
+	Repos   map[string]RepoStats // key: checkout (owner/name without #target)
+}
+
+// RepoStats is the slot usage of one checkout.
+type RepoStats struct {
+	Running int
+	Queued  int
+}
+
+// ewmaWeight is the weight of the latest run in the average run duration.
+const ewmaWeight = 0.2
+
+// Scheduler hands out run slots fairly across users and repositories.
+// Waiting requests are ordered by priority, then by how many runs their
+// user already has, then by arrival, so one busy user cannot starve others.
+type Scheduler struct {
+	mu      sync.Mutex
+	limits  Limits
+	running int
+	repos   map[string]int // key: checkout
+	users   map[string]int
+	queue   []*waiter
+	seq     uint64
+	avgRun  time.Duration // moving average of run durations, used for ETAs
+}
+
+type waiter struct {
+	req     Request
+	seq     uint64
+	ready   chan struct{} // closed when a slot is granted
+	granted bool
+	ahead   int
+	updates chan Position // latest position, buffered 1
+}
+
+func New(limits Limits) *Scheduler {
+	if limits.Global <= 0 {
+		limits.Global = 1
+	}
+	return &Scheduler{
+		limits: limits,
+		repos:  make(map[string]int),
+		users:  make(map[string]int),
+	}
+}
+
+// Acquire blocks until req may run or ctx is done. onWait, if set, is called
+// when the request has to queue and again whenever its position changes.
+// The returned function releases the slot and must be called exactly once.
+func (s *Scheduler) Acquire(ctx context.Context, req Request, onWait func(Position)) (func(), error) {
+	s.mu.Lock()
+	s.seq++
+	w := &waiter{
+		req:     req,
+		seq:     s.seq,
+		ready:   make(chan struct{}),
+		ahead:   -1,
+		updates: make(chan Position, 1),
+	}
+	s.queue = append(s.queue, w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	for {
+		select {
+		case <-w.ready:
+			return s.releaseFunc(req), nil
+		case pos := <-w.updates:
+			select {
+			case <-w.ready:
+				return s.releaseFunc(req), nil
+			default:
+			}
+			if onWait != nil {
+				onWait(pos)
+			}
+		case <-ctx.Done():
+			s.mu.Lock()
+			if w.granted {
+				// Granted concurrently with cancellation; hand the slot back
+				s.release(req)
+			} else {
+				s.remove(w)
+			}
+			s.dispatch()
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Stats returns the number of running and queued requests, overall and per checkout.
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repos := make(map[string]RepoStats)
+	for repo, n := range s.repos {
+		repos[repo] = RepoStats{Running: n}
+	}
+	for _, w := range s.queue {
+		checkout := domain.RepoKeyOf(w.req.Repo)
+		rs := repos[checkout]
+		rs.Queued++
+		repos[checkout] = rs
+	}
+	return Stats{Limits: s.limits, Running: s.running, Queued: len(s.queue), Repos: repos}
+}
+
+func (s *Scheduler) releaseFunc(req Request) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.observe(time.Since(start))
+			s.release(req)
+			s.dispatch()
+		})
+	}
+}
+
+// observe folds a finished run's duration into the average. Caller must hold mu.
+func (s *Scheduler) observe(d time.Duration) {
+	if s.avgRun == 0 {
+		s.avgRun = d
+		return
+	}
+	s.avgRun = time.Duration(ewmaWeight*float64(d) + (1-ewmaWeight)*float64(s.avgRun))
+}
+
+// release frees a slot. Caller must hold mu.
+func (s *Scheduler) release(req Request) {
+	s.running--
+	for _, repo := range req.checkouts() {
+		s.repos[repo]--
+		if s.repos[repo] <= 0 {
+			delete(s.repos, repo)
+		}
+	}
+	s.users[req.User]--
+	if s.users[req.User] <= 0 {
+		delete(s.users, req.User)
+	}
+}
+
+// remove drops w from the queue. Caller must hold mu.
+func (s *Scheduler) remove(w *waiter) {
+	for i, q := range s.queue {
+		if q == w {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// allowed reports whether req fits within the limits. Caller must hold mu.
+func (s *Scheduler) allowed(req Request) bool {
+	if s.running >= s.limits.Global {
+		return false
+	}
+	if s.limits.PerRepo > 0 {
+		for _, repo := range req.checkouts() {
+			if s.repos[repo] >= s.limits.PerRepo {
+				return false
+			}
+		}
+	}
+	if s.limits.PerUser > 0 && s.users[req.User] >= s.limits.PerUser {
+		return false
+	}
+	return true
+}
+
+// dispatch grants slots to eligible waiters in fair order, then refreshes the
+// positions of those still waiting. Caller must hold mu.
+func (s *Scheduler) dispatch() {
+	for {
+		s.sortQueue()
+		granted := false
+		for i, w := range s.queue {
+			if !s.allowed(w.req) {
+				continue
+			}
+			s.running++
+			for _, repo := range w.req.checkouts() {
+				s.repos[repo]++
+			}
+			s.users[w.req.User]++
+			w.granted = true
+			close(w.ready)
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			granted = true
+			break
+		}
+		if !granted {
+			break
+		}
+	}
+
+	for i, w := range s.queue {
+		if w.ahead == i {
+			continue
+		}
+		w.ahead = i
+		pos := Position{Ahead: i, ETA: s.eta(i)}
+		select {
+		case <-w.updates: // drop the stale position
+		default:
+		}
+		w.updates <- pos
+	}
+}
+
+// sortQueue orders waiters by priority, then by their user's running count,
+// then by arrival. Caller must hold mu.
+func (s *Scheduler) sortQueue() {
+	sort.SliceStable(s.queue, func(i, j int) bool {
+		a, b := s.queue[i], s.queue[j]
+		if a.req.Priority != b.req.Priority {
+			return a.req.Priority > b.req.Priority
+		}
+		if ua, ub := s.users[a.req.User], s.users[b.req.User]; ua != ub {
+			return ua < ub
+		}
+		return a.seq < b.seq
+	})
+}
+
+// eta estimates the wait for a request with the given number of waiters
+// ahead of it: running tasks are assumed half done, and each further round of
+// Global waiters takes one average run. Caller must hold mu.
+func (s *Scheduler) eta(ahead int) time.Duration {
+	if s.avgRun == 0 {
+		return 0
+	}
+	rounds := ahead / s.limits.Global
+	return s.avgRun/2 + time.Duration(rounds)*s.avgRun
+}
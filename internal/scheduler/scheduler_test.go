@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// grantOrder starts the running requests, queues reqs in order, then frees
+// slots one at a time, the running ones last first before the granted ones,
+// and returns the order in which the queued requests were granted.
+func grantOrder(t *testing.T, limits Limits, running []Request, reqs []Request) []string {
+	t.Helper()
+	s := New(limits)
+	ctx := context.Background()
+
+	var releases []func()
+	for _, r := range running {
+		release, err := s.Acquire(ctx, r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	type grant struct {
+		name    string
+		release func()
+	}
+	granted := make(chan grant, len(reqs))
+	for i, r := range reqs {
+		waiting := make(chan struct{})
+		go func(r Request) {
+			queued := false
+			release, err := s.Acquire(ctx, r, func(Position) {
+				if !queued {
+					queued = true
+					close(waiting)
+				}
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			granted <- grant{r.User + "@" + r.Repo, release}
+		}(r)
+		select {
+		case <-waiting:
+		case <-time.After(time.Second):
+			t.Fatalf("request %d was not queued", i)
+		}
+	}
+
+	// Each release frees one slot, so at most one request is granted at a time
+	var order []string
+	for len(releases) > 0 {
+		release := releases[len(releases)-1]
+		releases = releases[:len(releases)-1]
+		release()
+		select {
+		case g := <-granted:
+			order = append(order, g.name)
+			releases = append([]func(){g.release}, releases...)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return order
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		running []Request
+		reqs    []Request
+		want    []string
+	}{
+		{
+			name:    "arrival order",
+			limits:  Limits{Global: 1},
+			running: []Request{{User: "u0", Repo: "org/x"}},
+			reqs:    []Request{{User: "u1", Repo: "org/a"}, {User: "u2", Repo: "org/a"}, {User: "u3", Repo: "org/a"}},
+			want:    []string{"u1@org/a", "u2@org/a", "u3@org/a"},
+		},
+		{
+			name:    "priority first",
+			limits:  Limits{Global: 1},
+			running: []Request{{User: "u0", Repo: "org/x"}},
+			reqs:    []Request{{User: "u1", Repo: "org/a"}, {User: "u2", Repo: "org/a", Priority: 10}},
+			want:    []string{"u2@org/a", "u1@org/a"},
+		},
+		{
+			name:    "users with fewer runs first",
+			limits:  Limits{Global: 2},
+			running: []Request{{User: "busy", Repo: "org/x"}, {User: "other", Repo: "org/y"}},
+			reqs:    []Request{{User: "busy", Repo: "org/a"}, {User: "idle", Repo: "org/b"}},
+			want:    []string{"idle@org/b", "busy@org/a"},
+		},
+		{
+			name:    "per-repository limit skips a blocked request",
+			limits:  Limits{Global: 3, PerRepo: 1},
+			running: []Request{{User: "u0", Repo: "org/a"}, {User: "u0", Repo: "org/x"}, {User: "u0", Repo: "org/y"}},
+			reqs:    []Request{{User: "u1", Repo: "org/a"}, {User: "u2", Repo: "org/b"}},
+			want:    []string{"u2@org/b", "u1@org/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grantOrder(t, tt.limits, tt.running, tt.reqs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("granted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		running Request
+		req     Request
+		allowed bool
+	}{
+		{"global", Limits{Global: 1}, Request{User: "u1", Repo: "org/a"}, Request{User: "u2", Repo: "org/b"}, false},
+		{"per user", Limits{Global: 5, PerUser: 1}, Request{User: "u1", Repo: "org/a"}, Request{User: "u1", Repo: "org/b"}, false},
+		{"other user", Limits{Global: 5, PerUser: 1}, Request{User: "u1", Repo: "org/a"}, Request{User: "u2", Repo: "org/b"}, true},
+		{"per repository", Limits{Global: 5, PerRepo: 1}, Request{User: "u1", Repo: "org/a"}, Request{User: "u2", Repo: "org/a"}, false},
+		{"targets share the checkout", Limits{Global: 5, PerRepo: 1}, Request{User: "u1", Repo: "org/mono#web"}, Request{User: "u2", Repo: "org/mono#api"}, false},
+		{"related repository", Limits{Global: 5, PerRepo: 1}, Request{User: "u1", Repo: "org/a", Related: []string{"org/b"}}, Request{User: "u2", Repo: "org/b"}, false},
+		{"related of the waiting request", Limits{Global: 5, PerRepo: 1}, Request{User: "u1", Repo: "org/a"}, Request{User: "u2", Repo: "org/b", Related: []string{"org/a"}}, false},
+		{"unlimited per repository", Limits{Global: 5}, Request{User: "u1", Repo: "org/a"}, Request{User: "u2", Repo: "org/a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.limits)
+			release, err := s.Acquire(context.Background(), tt.running, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer release()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			release2, err := s.Acquire(ctx, tt.req, nil)
+			if got := err == nil; got != tt.allowed {
+				t.Errorf("allowed = %v, want %v", got, tt.allowed)
+			}
+			if release2 != nil {
+				release2()
+			}
+			if stats := s.Stats(); stats.Running != 1 || stats.Queued != 0 {
+				t.Errorf("after the second request: %d running, %d queued; want 1, 0", stats.Running, stats.Queued)
+			}
+		})
+	}
+}
+
+func TestSchedulerStatsAndETA(t *testing.T) {
+	s := New(Limits{Global: 1, PerRepo: 1})
+	ctx := context.Background()
+
+	release, err := s.Acquire(ctx, Request{User: "u1", Repo: "org/mono#web"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	release()
+	release() // releasing twice is a no-op
+
+	release, err = s.Acquire(ctx, Request{User: "u1", Repo: "org/mono#web"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := make(chan Position, 1)
+	waitCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, err := s.Acquire(waitCtx, Request{User: "u2", Repo: "org/mono#api"}, func(p Position) { positions <- p })
+		done <- err
+	}()
+
+	pos := <-positions
+	if pos.Ahead != 0 || pos.ETA <= 0 {
+		t.Errorf("position = %+v, want first in line with an ETA", pos)
+	}
+	want := Stats{
+		Limits:  Limits{Global: 1, PerRepo: 1},
+		Running: 1,
+		Queued:  1,
+		Repos:   map[string]RepoStats{"org/mono": {Running: 1, Queued: 1}},
+	}
+	if got := s.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	cancel()
+	if err := <-done; err == nil {
+		t.Error("cancelled Acquire returned no error")
+	}
+	release()
+	if got := s.Stats(); got.Running != 0 || got.Queued != 0 || len(got.Repos) != 0 {
+		t.Errorf("Stats() after release = %+v, want empty", got)
+	}
+}
+
+// TestSchedulerCancelRacingGrant cancels waiters while slots are being freed:
+// a slot granted to a cancelled waiter must be handed back, not leaked.
+func TestSchedulerCancelRacingGrant(t *testing.T) {
+	s := New(Limits{Global: 1, PerRepo: 1, PerUser: 1})
+	for i := 0; i < 200; i++ {
+		release, err := s.Acquire(context.Background(), Request{User: "u1", Repo: "org/a"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan func())
+		go func() {
+			r, _ := s.Acquire(ctx, Request{User: "u1", Repo: "org/a"}, nil)
+			done <- r
+		}()
+		released := make(chan struct{})
+		go func() {
+			release()
+			close(released)
+		}()
+		cancel()
+		if r := <-done; r != nil {
+			r()
+		}
+		<-released
+
+		if got := s.Stats(); got.Running != 0 || got.Queued != 0 {
+			t.Fatalf("iteration %d: %d running, %d queued after everything was released or cancelled", i, got.Running, got.Queued)
+		}
+	}
+}
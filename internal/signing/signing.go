@@ -0,0 +1,130 @@
+// Package signing configures git commit signing for agent runs through
+// GIT_CONFIG_* environment variables, so checkouts and the user's git config
+// are left untouched.
+package signing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/gitenv"
+)
+
+// Format is the signature format (git's gpg.format).
+type Format string
+
+const (
+	FormatSSH     Format = "ssh"
+	FormatOpenPGP Format = "openpgp"
+)
+
+// checkTimeout bounds the pre-run check; an agent waiting for a passphrase
+// would otherwise hang it.
+const checkTimeout = 30 * time.Second
+
+// Config is the signing configuration of a repository. The zero value
+// disables signing.
+type Config struct {
+	Format  Format
+	Key     string // ssh: private or public key file; openpgp: key ID
+	GPGHome string // openpgp: GNUPGHOME holding the key, "" for the default
+}
+
+// Enabled reports whether commits are signed.
+func (c Config) Enabled() bool {
+	return c.Format != ""
+}
+
+// Validate checks the format and that a key is given.
+func (c Config) Validate() error {
+	switch c.Format {
+	case "":
+		return nil
+	case FormatSSH, FormatOpenPGP:
+	default:
+		return fmt.Errorf("invalid signing format %q: must be ssh or openpgp", c.Format)
+	}
+	if c.Key == "" {
+		return fmt.Errorf("signing format %s requires a key", c.Format)
+	}
+	return nil
+}
+
+// GitConfig returns the git settings that make git sign every commit and tag.
+// They are passed with gitenv.Env together with any other settings of the run.
+func (c Config) GitConfig() []gitenv.Setting {
+	if !c.Enabled() {
+		return nil
+	}
+	return []gitenv.Setting{
+		{Key: "commit.gpgsign", Value: "true"},
+		{Key: "tag.gpgsign", Value: "true"},
+		{Key: "gpg.format", Value: string(c.Format)},
+		{Key: "user.signingkey", Value: c.Key},
+	}
+}
+
+// Env returns the environment signing needs besides the git settings.
+func (c Config) Env() []string {
+	if c.Format == FormatOpenPGP && c.GPGHome != "" {
+		return []string{"GNUPGHOME=" + c.GPGHome}
+	}
+	return nil
+}
+
+// Check makes a signed commit in a scratch repository with c's environment
+// and returns why signing failed, if it did.
+func Check(ctx context.Context, c Config) error {
+	if !c.Enabled() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "signing-check-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	env := append(append(os.Environ(), gitenv.Env(c.GitConfig())...), c.Env()...)
+	env = append(env,
+		"GIT_AUTHOR_NAME=signing check", "GIT_AUTHOR_EMAIL=signing-check@localhost",
+		"GIT_COMMITTER_NAME=signing check", "GIT_COMMITTER_EMAIL=signing-check@localhost",
+		"GIT_TERMINAL_PROMPT=0")
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("git %s: timed out (is the key protected by a passphrase?)", args[0])
+			}
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return string(out), nil
+	}
+
+	if _, err := git("init", "-q"); err != nil {
+		return err
+	}
+	if _, err := git("commit", "-q", "--allow-empty", "-m", "signing check"); err != nil {
+		return fmt.Errorf("signed commit failed with %s key %s: %w", c.Format, c.Key, err)
+	}
+	commit, err := git("cat-file", "commit", "HEAD")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(commit, "\ngpgsig ") {
+		return fmt.Errorf("commit was created without a signature")
+	}
+	return nil
+}
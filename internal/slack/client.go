@@ -1,9 +1,17 @@
 package slack
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
 )
 
 type Client struct {
@@ -16,54 +24,195 @@ func NewClient(api *slack.Client) *Client {
 	}
 }
 
-func (c *Client) AddReaction(channel, timestamp, emoji string) error {
+func (c *Client) AddReaction(ctx context.Context, channel, timestamp, emoji string) error {
+	ctx, span := c.startSpan(ctx, "reactions.add", channel)
+	defer span.End()
+
 	ref := slack.NewRefToMessage(channel, timestamp)
-	return c.api.AddReaction(emoji, ref)
+	return c.track(span, "reactions.add", c.api.AddReactionContext(ctx, emoji, ref))
 }
 
-func (c *Client) PostMessage(channel, text string) error {
-	_, _, err := c.api.PostMessage(
-		channel,
-		slack.MsgOptionText(text, false),
-	)
+func (c *Client) PostMessage(ctx context.Context, channel, text string) error {
+	_, err := c.PostMessageReturningTS(ctx, channel, text)
 	return err
 }
 
-func (c *Client) PostMessageReturningTS(channel, text string) (string, error) {
-	_, ts, err := c.api.PostMessage(
+func (c *Client) PostMessageReturningTS(ctx context.Context, channel, text string) (string, error) {
+	ctx, span := c.startSpan(ctx, "chat.postMessage", channel)
+	defer span.End()
+
+	_, ts, err := c.api.PostMessageContext(
+		ctx,
 		channel,
 		slack.MsgOptionText(text, false),
 	)
-	return ts, err
+	return ts, c.track(span, "chat.postMessage", err)
 }
 
-func (c *Client) PostThreadMessage(channel, threadTS, text string) error {
-	_, _, err := c.api.PostMessage(
-		channel,
-		slack.MsgOptionText(text, false),
-		slack.MsgOptionTS(threadTS),
-	)
+func (c *Client) PostThreadMessage(ctx context.Context, channel, threadTS, text string) error {
+	_, err := c.PostThreadMessageReturningTS(ctx, channel, threadTS, text)
 	return err
 }
 
-func (c *Client) PostThreadMessageReturningTS(channel, threadTS, text string) (string, error) {
-	_, ts, err := c.api.PostMessage(
+func (c *Client) PostThreadMessageReturningTS(ctx context.Context, channel, threadTS, text string) (string, error) {
+	ctx, span := c.startSpan(ctx, "chat.postMessage", channel)
+	defer span.End()
+
+	_, ts, err := c.api.PostMessageContext(
+		ctx,
 		channel,
 		slack.MsgOptionText(text, false),
 		slack.MsgOptionTS(threadTS),
 	)
-	return ts, err
+	return ts, c.track(span, "chat.postMessage", err)
 }
 
-func (c *Client) UpdateThreadMessage(channel, messageTS, text string) error {
-	_, _, _, err := c.api.UpdateMessage(
+func (c *Client) UpdateThreadMessage(ctx context.Context, channel, messageTS, text string) error {
+	ctx, span := c.startSpan(ctx, "chat.update", channel)
+	defer span.End()
+
+	_, _, _, err := c.api.UpdateMessageContext(
+		ctx,
 		channel,
 		messageTS,
 		slack.MsgOptionText(text, false),
 	)
-	return err
+	return c.track(span, "chat.update", err)
+}
+
+// UploadFile uploads text content as a file to a channel (or thread if threadTS is set).
+func (c *Client) UploadFile(ctx context.Context, channel, threadTS, filename, title, content string) error {
+	ctx, span := c.startSpan(ctx, "files.upload", channel)
+	defer span.End()
+
+	_, err := c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Channel:         channel,
+		ThreadTimestamp: threadTS,
+		Filename:        filename,
+		Title:           title,
+		Content:         content,
+		FileSize:        len(content),
+	})
+	return c.track(span, "files.upload", err)
+}
+
+// Section is a block of mrkdwn text, optionally with a button on its right.
+type Section struct {
+	Text   string
+	Button *Button
+}
+
+// Button is an interactive button. Clicks are delivered to MentionHandler.HandleBlockAction.
+type Button struct {
+	ActionID string
+	Value    string
+	Text     string
+	Danger   bool // red style, with a confirmation dialog
+}
+
+// PostThreadSections posts a message made of sections to a channel (or thread if threadTS is set).
+// text is the notification fallback.
+func (c *Client) PostThreadSections(ctx context.Context, channel, threadTS, text string, sections []Section) error {
+	ctx, span := c.startSpan(ctx, "chat.postMessage", channel)
+	defer span.End()
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(buildBlocks(sections)...),
+	}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	_, _, err := c.api.PostMessageContext(ctx, channel, opts...)
+	return c.track(span, "chat.postMessage", err)
 }
 
-func (c *Client) NotifyError(channel, threadTS string, err error) {
-	c.PostThreadMessage(channel, threadTS, fmt.Sprintf("エラーが発生しました: %s", err.Error()))
+// PostEphemeralSections posts a message made of sections visible only to user.
+func (c *Client) PostEphemeralSections(ctx context.Context, channel, user, text string, sections []Section) error {
+	ctx, span := c.startSpan(ctx, "chat.postEphemeral", channel)
+	defer span.End()
+
+	_, err := c.api.PostEphemeralContext(ctx, channel, user,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(buildBlocks(sections)...),
+	)
+	return c.track(span, "chat.postEphemeral", err)
+}
+
+// PostEphemeral posts a plain text message visible only to user.
+func (c *Client) PostEphemeral(ctx context.Context, channel, user, text string) error {
+	return c.PostEphemeralSections(ctx, channel, user, text, []Section{{Text: text}})
+}
+
+func buildBlocks(sections []Section) []slack.Block {
+	blocks := make([]slack.Block, 0, len(sections))
+	for _, s := range sections {
+		text := slack.NewTextBlockObject(slack.MarkdownType, s.Text, false, false)
+		var accessory *slack.Accessory
+		if s.Button != nil {
+			btn := slack.NewButtonBlockElement(s.Button.ActionID, s.Button.Value,
+				slack.NewTextBlockObject(slack.PlainTextType, s.Button.Text, false, false))
+			if s.Button.Danger {
+				btn.Style = slack.StyleDanger
+				btn.Confirm = slack.NewConfirmationBlockObject(
+					slack.NewTextBlockObject(slack.PlainTextType, s.Button.Text, false, false),
+					slack.NewTextBlockObject(slack.PlainTextType, "本当に実行しますか？", false, false),
+					slack.NewTextBlockObject(slack.PlainTextType, "実行", false, false),
+					slack.NewTextBlockObject(slack.PlainTextType, "キャンセル", false, false),
+				)
+			}
+			accessory = slack.NewAccessory(btn)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, accessory))
+	}
+	return blocks
+}
+
+func (c *Client) NotifyError(ctx context.Context, channel, threadTS string, err error) {
+	c.PostThreadMessage(ctx, channel, threadTS, fmt.Sprintf("エラーが発生しました: %s", err.Error()))
+}
+
+// DownloadFile downloads a private Slack file (url_private_download) using the bot token.
+func (c *Client) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	ctx, span := c.startSpan(ctx, "files.download", "")
+	defer span.End()
+
+	return c.track(span, "files.download", c.api.GetFileContext(ctx, url, w))
+}
+
+// UserProfile returns the real (or display) name and email of a Slack user.
+// The email requires the users:read.email scope.
+func (c *Client) UserProfile(ctx context.Context, userID string) (name, email string, err error) {
+	ctx, span := c.startSpan(ctx, "users.info", "")
+	defer span.End()
+
+	user, err := c.api.GetUserInfoContext(ctx, userID)
+	if err = c.track(span, "users.info", err); err != nil {
+		return "", "", err
+	}
+	name = user.Profile.RealName
+	if name == "" {
+		name = user.Profile.DisplayName
+	}
+	return name, user.Profile.Email, nil
+}
+
+// startSpan starts a client span for a Slack Web API call.
+func (c *Client) startSpan(ctx context.Context, method, channel string) (context.Context, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, "slack "+method, trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(attribute.String("slack.method", method))
+	if channel != "" {
+		span.SetAttributes(attribute.String("slack.channel", channel))
+	}
+	return ctx, span
+}
+
+// track records failed Slack API calls in metrics and on the span, and passes the error through.
+func (c *Client) track(span trace.Span, method string, err error) error {
+	if err != nil {
+		metrics.SlackAPIErrors.Inc(method)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
 }
@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Call is a Slack Web API call recorded by FakeClient.
+type Call struct {
+	Method   string // e.g. "chat.postMessage", "chat.update"
+	Channel  string
+	ThreadTS string
+	TS       string // timestamp of the posted or updated message
+	User     string // recipient of ephemeral messages
+	Text     string
+	Sections []Section
+	Filename string // for files.upload
+}
+
+// FakeClient implements the methods of Client without talking to Slack.
+// Every call is recorded and messages get sequential timestamps, so runs can
+// be replayed and their Slack output compared offline.
+type FakeClient struct {
+	mu     sync.Mutex
+	calls  []Call
+	nextTS int
+
+	// Files maps download URLs to their content for DownloadFile.
+	Files map[string][]byte
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{Files: make(map[string][]byte)}
+}
+
+// Calls returns the recorded calls in order.
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Transcript renders the recorded calls as text, one call per paragraph.
+func (f *FakeClient) Transcript() string {
+	var sb strings.Builder
+	for _, c := range f.Calls() {
+		fmt.Fprintf(&sb, "--- %s", c.Method)
+		for _, kv := range [][2]string{{"channel", c.Channel}, {"thread", c.ThreadTS}, {"ts", c.TS}, {"user", c.User}, {"file", c.Filename}} {
+			if kv[1] != "" {
+				fmt.Fprintf(&sb, " %s=%s", kv[0], kv[1])
+			}
+		}
+		sb.WriteString("\n")
+		if len(c.Sections) > 0 {
+			for _, s := range c.Sections {
+				sb.WriteString(s.Text + "\n")
+				if s.Button != nil {
+					fmt.Fprintf(&sb, "[%s: %s=%s]\n", s.Button.Text, s.Button.ActionID, s.Button.Value)
+				}
+			}
+		} else if c.Text != "" {
+			sb.WriteString(c.Text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// record appends a call, assigning a new message timestamp if ts is empty.
+func (f *FakeClient) record(c Call) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.TS == "" {
+		f.nextTS++
+		c.TS = fmt.Sprintf("1700000000.%06d", f.nextTS)
+	}
+	f.calls = append(f.calls, c)
+	return c.TS
+}
+
+func (f *FakeClient) AddReaction(ctx context.Context, channel, timestamp, emoji string) error {
+	f.record(Call{Method: "reactions.add", Channel: channel, TS: timestamp, Text: ":" + emoji + ":"})
+	return nil
+}
+
+func (f *FakeClient) PostMessage(ctx context.Context, channel, text string) error {
+	_, err := f.PostMessageReturningTS(ctx, channel, text)
+	return err
+}
+
+func (f *FakeClient) PostMessageReturningTS(ctx context.Context, channel, text string) (string, error) {
+	return f.record(Call{Method: "chat.postMessage", Channel: channel, Text: text}), nil
+}
+
+func (f *FakeClient) PostThreadMessage(ctx context.Context, channel, threadTS, text string) error {
+	_, err := f.PostThreadMessageReturningTS(ctx, channel, threadTS, text)
+	return err
+}
+
+func (f *FakeClient) PostThreadMessageReturningTS(ctx context.Context, channel, threadTS, text string) (string, error) {
+	return f.record(Call{Method: "chat.postMessage", Channel: channel, ThreadTS: threadTS, Text: text}), nil
+}
+
+func (f *FakeClient) UpdateThreadMessage(ctx context.Context, channel, messageTS, text string) error {
+	f.record(Call{Method: "chat.update", Channel: channel, TS: messageTS, Text: text})
+	return nil
+}
+
+func (f *FakeClient) UploadFile(ctx context.Context, channel, threadTS, filename, title, content string) error {
+	f.record(Call{Method: "files.upload", Channel: channel, ThreadTS: threadTS, Filename: filename, Text: content})
+	return nil
+}
+
+func (f *FakeClient) PostThreadSections(ctx context.Context, channel, threadTS, text string, sections []Section) error {
+	f.record(Call{Method: "chat.postMessage", Channel: channel, ThreadTS: threadTS, Text: text, Sections: sections})
+	return nil
+}
+
+func (f *FakeClient) PostEphemeralSections(ctx context.Context, channel, user, text string, sections []Section) error {
+	f.record(Call{Method: "chat.postEphemeral", Channel: channel, User: user, Text: text, Sections: sections})
+	return nil
+}
+
+func (f *FakeClient) PostEphemeral(ctx context.Context, channel, user, text string) error {
+	return f.PostEphemeralSections(ctx, channel, user, text, []Section{{Text: text}})
+}
+
+func (f *FakeClient) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	f.mu.Lock()
+	data, ok := f.Files[url]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("fake slack: no file at %s", url)
+	}
+	_, err := w.Write(data)
+	return err
+}
@@ -2,19 +2,36 @@ package slack
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/toshin/slack-claude-agent/internal/metrics"
+	"github.com/toshin/slack-claude-agent/internal/tracing"
 )
 
 type MentionHandler interface {
-	HandleMention(event Event)
-	HandleThreadMessage(event Event)
-	HandleSlashCommand(command, text, channel, user, responseURL string)
+	HandleMention(ctx context.Context, event Event)
+	HandleThreadMessage(ctx context.Context, event Event)
+	HandleSlashCommand(ctx context.Context, command, text, channel, user, responseURL string)
+	HandleBlockAction(ctx context.Context, action BlockAction)
+}
+
+// BlockAction is a click on an interactive element (e.g. a button) posted by the bot.
+type BlockAction struct {
+	ActionID string
+	Value    string
+	User     string
+	Channel  string
 }
 
 type Event struct {
@@ -24,6 +41,16 @@ type Event struct {
 	Channel  string `json:"channel"`
 	TS       string `json:"ts"`
 	ThreadTS string `json:"thread_ts,omitempty"` // Thread timestamp (if in a thread)
+	Files    []File `json:"files,omitempty"`     // Attachments shared with the message
+}
+
+// File is an attachment (screenshot, log, CSV, ...) shared with a message.
+type File struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Mimetype string `json:"mimetype"`
+	Size     int    `json:"size"`
+	URL      string `json:"url_private_download"` // requires the bot token to download
 }
 
 type Handler struct {
@@ -31,6 +58,7 @@ type Handler struct {
 	socketClient    *socketmode.Client
 	mentionHandler  MentionHandler
 	processedEvents sync.Map
+	connected       atomic.Bool // Socket Mode connection is up
 }
 
 func NewHandler(appToken, botToken string, mentionHandler MentionHandler) *Handler {
@@ -76,8 +104,24 @@ func (h *Handler) handleEvents(ctx context.Context) {
 	}
 }
 
+// Connected reports whether the Socket Mode connection is currently established.
+func (h *Handler) Connected() bool {
+	return h.connected.Load()
+}
+
 func (h *Handler) processEvent(evt socketmode.Event) {
+	metrics.EventsReceived.Inc(string(evt.Type))
+
 	switch evt.Type {
+	case socketmode.EventTypeConnected:
+		h.connected.Store(true)
+		slog.Info("socket mode connected")
+
+	case socketmode.EventTypeConnecting, socketmode.EventTypeDisconnect, socketmode.EventTypeConnectionError:
+		if h.connected.Swap(false) {
+			slog.Warn("socket mode disconnected", "event", evt.Type)
+		}
+
 	case socketmode.EventTypeEventsAPI:
 		eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
 		if !ok {
@@ -117,8 +161,13 @@ func (h *Handler) processEvent(evt socketmode.Event) {
 					Channel:  ev.Channel,
 					TS:       ev.TimeStamp,
 					ThreadTS: ev.ThreadTimeStamp, // Thread timestamp for replies
+					Files:    extractMentionFiles(evt.Request.Payload),
 				}
-				go h.mentionHandler.HandleMention(event)
+				go func() {
+					ctx, span := startEventSpan("app_mention", event.Channel, event.User)
+					defer span.End()
+					h.mentionHandler.HandleMention(ctx, event)
+				}()
 
 			case *slackevents.MessageEvent:
 				// Only handle thread messages (not bot messages)
@@ -151,11 +200,21 @@ func (h *Handler) processEvent(evt socketmode.Event) {
 						TS:       ev.TimeStamp,
 						ThreadTS: ev.ThreadTimeStamp,
 					}
-					go h.mentionHandler.HandleThreadMessage(event)
+					if ev.Message != nil {
+						event.Files = convertFiles(ev.Message.Files)
+					}
+					go func() {
+						ctx, span := startEventSpan("thread_message", event.Channel, event.User)
+						defer span.End()
+						h.mentionHandler.HandleThreadMessage(ctx, event)
+					}()
 				}
 			}
 		}
 
+	case socketmode.EventTypeInteractive:
+		h.processInteraction(evt)
+
 	case socketmode.EventTypeSlashCommand:
 		cmd, ok := evt.Data.(slack.SlashCommand)
 		if !ok {
@@ -169,14 +228,100 @@ func (h *Handler) processEvent(evt socketmode.Event) {
 			"user", cmd.UserID,
 		)
 
-		go h.mentionHandler.HandleSlashCommand(
-			cmd.Command,
-			cmd.Text,
-			cmd.ChannelID,
-			cmd.UserID,
-			cmd.ResponseURL,
+		go func() {
+			ctx, span := startEventSpan("slash_command", cmd.ChannelID, cmd.UserID)
+			span.SetAttributes(attribute.String("slack.command", cmd.Command))
+			defer span.End()
+			h.mentionHandler.HandleSlashCommand(
+				ctx,
+				cmd.Command,
+				cmd.Text,
+				cmd.ChannelID,
+				cmd.UserID,
+				cmd.ResponseURL,
+			)
+		}()
+	}
+}
+
+// processInteraction handles block_actions payloads (button clicks).
+func (h *Handler) processInteraction(evt socketmode.Event) {
+	callback, ok := evt.Data.(slack.InteractionCallback)
+	if !ok {
+		return
+	}
+	h.socketClient.Ack(*evt.Request)
+
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		slog.Info("received block_action",
+			"action_id", action.ActionID,
+			"channel", callback.Channel.ID,
+			"user", callback.User.ID,
 		)
+
+		ba := BlockAction{
+			ActionID: action.ActionID,
+			Value:    action.Value,
+			User:     callback.User.ID,
+			Channel:  callback.Channel.ID,
+		}
+		go func() {
+			ctx, span := startEventSpan("block_action", ba.Channel, ba.User)
+			span.SetAttributes(attribute.String("slack.action_id", ba.ActionID))
+			defer span.End()
+			h.mentionHandler.HandleBlockAction(ctx, ba)
+		}()
+	}
+}
+
+// startEventSpan starts the root span for handling an incoming Slack event.
+func startEventSpan(eventType, channel, user string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(context.Background(), "slack.event "+eventType,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("slack.event_type", eventType),
+			attribute.String("slack.channel", channel),
+			attribute.String("slack.user", user),
+		))
+}
+
+// extractMentionFiles reads the files array from the raw app_mention payload.
+// slackevents.AppMentionEvent does not expose it, so we decode it ourselves.
+func extractMentionFiles(payload json.RawMessage) []File {
+	var envelope struct {
+		Event struct {
+			Files []slack.File `json:"files"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil
+	}
+	return convertFiles(envelope.Event.Files)
+}
+
+func convertFiles(files []slack.File) []File {
+	var result []File
+	for _, f := range files {
+		url := f.URLPrivateDownload
+		if url == "" {
+			url = f.URLPrivate
+		}
+		if url == "" {
+			continue // external or deleted file
+		}
+		result = append(result, File{
+			ID:       f.ID,
+			Name:     f.Name,
+			Mimetype: f.Mimetype,
+			Size:     f.Size,
+			URL:      url,
+		})
 	}
+	return result
 }
 
 func (h *Handler) cleanupLoop() {
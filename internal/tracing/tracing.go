@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "slack-claude-agent"
+	tracerName  = "github.com/toshin/slack-claude-agent"
+)
+
+// Exporter names accepted by Setup.
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"   // OTLP/HTTP; endpoint from OTEL_EXPORTER_OTLP_ENDPOINT (default localhost:4318)
+	ExporterStdout = "stdout" // pretty-printed spans on stderr
+)
+
+// Setup installs the global tracer provider for the given exporter.
+// The returned function flushes and stops the provider.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		// stdout carries the JSON logs, so spans go to stderr
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q (expected otlp or stdout)", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the agent's tracer. It is a no-op until Setup installs a provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// TraceID returns the trace ID of the span in ctx, or "" if there is none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
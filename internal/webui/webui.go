@@ -0,0 +1,217 @@
+package webui
+
+import (
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toshin/slack-claude-agent/internal/agent"
+	"github.com/toshin/slack-claude-agent/internal/claude"
+	"github.com/toshin/slack-claude-agent/internal/ledger"
+	"github.com/toshin/slack-claude-agent/internal/runstore"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// cookieName holds the shared token after logging in.
+const cookieName = "slack_claude_ui_token"
+
+// maxListedRuns caps the runs shown on the index page.
+const maxListedRuns = 200
+
+// Config holds the dependencies of the web UI.
+type Config struct {
+	Token    string // shared secret required for every page
+	Runs     *runstore.Store
+	Sessions func() []agent.SessionInfo
+}
+
+// Server serves the web UI under /ui/.
+type Server struct {
+	cfg       Config
+	templates map[string]*template.Template
+	logger    *slog.Logger
+}
+
+func New(cfg Config, logger *slog.Logger) (*Server, error) {
+	funcs := template.FuncMap{
+		"duration": func(ms int64) string { return (time.Duration(ms) * time.Millisecond).Round(time.Second).String() },
+		"datetime": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+		"ago":      func(t time.Time) string { return time.Since(t).Round(time.Second).String() },
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, page := range []string{"index.html", "run.html", "login.html"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page)
+		if err != nil {
+			return nil, err
+		}
+		templates[page] = t
+	}
+
+	return &Server{cfg: cfg, templates: templates, logger: logger}, nil
+}
+
+// Register adds the UI routes to mux.
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /ui/login", s.handleLoginForm)
+	mux.HandleFunc("POST /ui/login", s.handleLogin)
+	mux.Handle("GET /ui/{$}", s.requireToken(http.HandlerFunc(s.handleIndex)))
+	mux.Handle("GET /ui/runs/{id}", s.requireToken(http.HandlerFunc(s.handleRun)))
+}
+
+// requireToken lets requests through that carry the token in the cookie or an
+// Authorization: Bearer header, and redirects the rest to the login page.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if c, err := r.Cookie(cookieName); err == nil {
+			token = c.Value
+		}
+		if !s.validToken(token) {
+			http.Redirect(w, r, "/ui/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) validToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) == 1
+}
+
+func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "login.html", map[string]any{"Next": r.URL.Query().Get("next")})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/ui/") {
+		next = "/ui/"
+	}
+
+	if !s.validToken(r.FormValue("token")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		s.render(w, "login.html", map[string]any{"Next": next, "Error": "トークンが正しくありません"})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    r.FormValue("token"),
+		Path:     "/ui/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.cfg.Runs.List(maxListedRuns)
+	if err != nil {
+		s.logger.Error("failed to list runs", "error", err)
+		http.Error(w, "failed to list runs", http.StatusInternalServerError)
+		return
+	}
+
+	var total float64
+	for _, run := range runs {
+		total += run.Cost
+	}
+
+	s.render(w, "index.html", map[string]any{
+		"Sessions":  s.cfg.Sessions(),
+		"Runs":      runs,
+		"TotalCost": total,
+	})
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	meta, err := s.cfg.Runs.Meta(id)
+	if errors.Is(err, runstore.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to read run", "error", err, "task_id", id)
+		http.Error(w, "failed to read run", http.StatusInternalServerError)
+		return
+	}
+
+	var entries []claude.TranscriptEntry
+	if stream, err := s.cfg.Runs.OpenStream(id); err == nil {
+		entries, err = claude.ReadTranscript(stream)
+		stream.Close()
+		if err != nil {
+			s.logger.Warn("failed to read transcript", "error", err, "task_id", id)
+		}
+	}
+
+	var stderr string
+	if f, err := s.cfg.Runs.OpenStderr(id); err == nil {
+		data, _ := io.ReadAll(f)
+		f.Close()
+		stderr = string(data)
+	}
+
+	diff, err := s.cfg.Runs.Diff(id)
+	if err != nil {
+		s.logger.Warn("failed to read diff", "error", err, "task_id", id)
+	}
+
+	s.render(w, "run.html", map[string]any{
+		"Run":     meta,
+		"Entries": entries,
+		"Stderr":  stderr,
+		"Diff":    diffLines(diff),
+		"Success": meta.Outcome == ledger.OutcomeSuccess,
+	})
+}
+
+func (s *Server) render(w http.ResponseWriter, page string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates[page].Execute(w, data); err != nil {
+		s.logger.Error("failed to render page", "error", err, "page", page)
+	}
+}
+
+// diffLine is a line of a unified diff with its CSS class.
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+func diffLines(diff string) []diffLine {
+	if diff == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	result := make([]diffLine, 0, len(lines))
+	for _, l := range lines {
+		class := ""
+		switch {
+		case strings.HasPrefix(l, "diff --git"):
+			class = "file"
+		case strings.HasPrefix(l, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "+++"):
+			class = "add"
+		case strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "---"):
+			class = "del"
+		}
+		result = append(result, diffLine{Class: class, Text: l})
+	}
+	return result
+}
@@ -0,0 +1,194 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+	"github.com/toshin/slack-claude-agent/internal/githost"
+)
+
+// Bootstrapper makes sure every repository has a checkout where the layout
+// puts it, cloning missing ones.
+type Bootstrapper struct {
+	layout Layout
+	opts   BootstrapOptions
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	status map[string]error // last Ensure result per repository key
+}
+
+// BootstrapOptions configures a Bootstrapper.
+type BootstrapOptions struct {
+	Clone bool // false only verifies existing checkouts
+
+	// CloneURL returns where a repository is cloned from; nil or "" means
+	// GitHub (with gh when it is installed).
+	CloneURL func(repo *domain.Repository) string
+
+	// Env returns extra environment variables for cloning a repository,
+	// such as GH_HOST for a GitHub Enterprise server; nil means none.
+	Env func(repo *domain.Repository) []string
+}
+
+func NewBootstrapper(layout Layout, opts BootstrapOptions, logger *slog.Logger) *Bootstrapper {
+	return &Bootstrapper{layout: layout, opts: opts, logger: logger, status: make(map[string]error)}
+}
+
+// Dir returns the checkout directory of repo.
+func (b *Bootstrapper) Dir(repo *domain.Repository) string {
+	return b.layout.Dir(repo)
+}
+
+// WorkDir returns the directory the agent works in for repo.
+func (b *Bootstrapper) WorkDir(repo *domain.Repository) string {
+	return b.layout.WorkDir(repo)
+}
+
+// Migrate moves a flat checkout of repo to the owner-qualified layout (see
+// Layout.Migrate).
+func (b *Bootstrapper) Migrate(repo *domain.Repository) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dir, err := b.layout.Migrate(repo)
+	if dir != "" {
+		b.logger.Info("moved checkout to owner-qualified path", "repository", repo.RepoKey(), "dir", dir)
+	}
+	return err
+}
+
+// Ensure clones repo into Dir(repo) if it has no checkout yet and verifies the
+// checkout's origin points to repo. The result is kept for Status.
+func (b *Bootstrapper) Ensure(ctx context.Context, repo *domain.Repository) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.ensure(ctx, repo)
+	b.status[repo.Key()] = err
+	return err
+}
+
+func (b *Bootstrapper) ensure(ctx context.Context, repo *domain.Repository) error {
+	dir := b.Dir(repo)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if !b.opts.Clone {
+			return fmt.Errorf("no checkout at %s", dir)
+		}
+		if err := b.cloneRepo(ctx, repo, dir); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	owner, name, err := remoteRepo(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if !sameRepo(owner, name, repo) {
+		return fmt.Errorf("%s: origin is %s/%s, expected %s", dir, owner, name, repo.RepoKey())
+	}
+
+	if repo.Target.Dir != "" {
+		if info, err := os.Stat(b.WorkDir(repo)); err != nil || !info.IsDir() {
+			return fmt.Errorf("target %s: directory %s not found in %s", repo.Target.Name, repo.Target.Dir, repo.RepoKey())
+		}
+	}
+	return nil
+}
+
+// cloneRepo clones from the repository's clone URL. GitHub repositories are
+// cloned with gh when available (so its authentication covers private
+// repositories), otherwise with git over HTTPS.
+func (b *Bootstrapper) cloneRepo(ctx context.Context, repo *domain.Repository, dir string) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s exists but is not a git checkout", dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return err
+	}
+
+	source := fmt.Sprintf("https://github.com/%s.git", repo.RepoKey())
+	if b.opts.CloneURL != nil {
+		if url := b.opts.CloneURL(repo); url != "" {
+			source = url
+		}
+	}
+
+	var env []string
+	if b.opts.Env != nil {
+		env = b.opts.Env(repo)
+	}
+
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("gh"); err == nil && onGitHub(source, env) {
+		cmd = exec.CommandContext(ctx, "gh", "repo", "clone", repo.RepoKey(), dir)
+		cmd.Env = append(os.Environ(), env...)
+	} else {
+		cmd = exec.CommandContext(ctx, "git", "clone", source, dir)
+		cmd.Env = append(append(os.Environ(), env...), "GIT_TERMINAL_PROMPT=0") // fail instead of waiting for credentials
+	}
+	b.logger.Info("cloning repository", "repository", repo.RepoKey(), "dir", dir, "command", cmd.Args[0])
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir) // don't leave a partial clone behind for the next attempt
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("clone %s: %w: %s", repo.RepoKey(), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// onGitHub reports whether source is an HTTPS URL on the server gh uses with
+// env: github.com, or the GitHub Enterprise server named by GH_HOST.
+func onGitHub(source string, env []string) bool {
+	hostname := "github.com"
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "GH_HOST="); ok {
+			hostname = v
+		}
+	}
+	return strings.HasPrefix(source, "https://"+hostname+"/")
+}
+
+// Status returns the last Ensure result per repository key (nil = ready).
+func (b *Bootstrapper) Status() map[string]error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := make(map[string]error, len(b.status))
+	for key, err := range b.status {
+		status[key] = err
+	}
+	return status
+}
+
+// remoteRepo returns the owner and name of the origin remote of the checkout in dir.
+func remoteRepo(ctx context.Context, dir string) (owner, name string, err error) {
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: get origin url: %w", dir, err)
+	}
+	remote, err := githost.ParseRemote(string(out))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", dir, err)
+	}
+	return remote.Owner, remote.Name, nil
+}
+
+func sameRepo(owner, name string, repo *domain.Repository) bool {
+	return strings.EqualFold(owner, repo.Owner) && strings.EqualFold(name, repo.Name)
+}
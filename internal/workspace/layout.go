@@ -0,0 +1,88 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/toshin/slack-claude-agent/internal/domain"
+)
+
+// Layout maps repositories to checkout directories. By default a checkout
+// lives at <Root>/<owner>/<name>; an existing flat <Root>/<name> checkout of
+// the same repository is still used until it is migrated.
+type Layout struct {
+	Root  string
+	Paths map[string]string // explicit checkout per owner/name; relative paths are under Root
+}
+
+// Dir returns the checkout directory of repo. Sub-project targets of a
+// repository share its checkout.
+func (l Layout) Dir(repo *domain.Repository) string {
+	if path, ok := l.Paths[repo.RepoKey()]; ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(l.Root, path)
+		}
+		return path
+	}
+	if flat := l.flatDir(repo); holds(flat, repo) {
+		return flat
+	}
+	return l.qualifiedDir(repo)
+}
+
+// Migrate moves a flat <Root>/<name> checkout of repo to <Root>/<owner>/<name>
+// and returns the new directory, or "" if there was nothing to move.
+// Repositories with an explicit path are left alone. It must not run while a
+// task uses the checkout.
+func (l Layout) Migrate(repo *domain.Repository) (string, error) {
+	if _, ok := l.Paths[repo.RepoKey()]; ok {
+		return "", nil
+	}
+	flat, qualified := l.flatDir(repo), l.qualifiedDir(repo)
+	if !holds(flat, repo) {
+		return "", nil
+	}
+	if _, err := os.Stat(qualified); err == nil {
+		return "", fmt.Errorf("cannot move %s: %s already exists", flat, qualified)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	// Move through a temporary name: with owner == name the target is inside the source
+	tmp := filepath.Join(l.Root, ".migrating-"+repo.Owner+"-"+repo.Name)
+	if err := os.Rename(flat, tmp); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(qualified), 0o755); err != nil {
+		os.Rename(tmp, flat)
+		return "", err
+	}
+	if err := os.Rename(tmp, qualified); err != nil {
+		os.Rename(tmp, flat)
+		return "", err
+	}
+	return qualified, nil
+}
+
+func (l Layout) flatDir(repo *domain.Repository) string {
+	return filepath.Join(l.Root, repo.Name)
+}
+
+func (l Layout) qualifiedDir(repo *domain.Repository) string {
+	return filepath.Join(l.Root, repo.Owner, repo.Name)
+}
+
+// WorkDir returns the directory the agent works in: the checkout, or the
+// target's subdirectory of it.
+func (l Layout) WorkDir(repo *domain.Repository) string {
+	return filepath.Join(l.Dir(repo), repo.Target.Dir)
+}
+
+// holds reports whether dir is a checkout of repo.
+func holds(dir string, repo *domain.Repository) bool {
+	owner, name, err := remoteRepo(context.Background(), dir)
+	return err == nil && sameRepo(owner, name, repo)
+}
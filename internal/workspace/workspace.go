@@ -0,0 +1,237 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirtyPolicy decides what happens to uncommitted changes left in a checkout,
+// and to local commits on the branch that were never pushed: those are saved
+// to a backup branch (stash) or make Prepare fail with an *UnpushedError (refuse).
+type DirtyPolicy string
+
+const (
+	DirtyStash  DirtyPolicy = "stash"  // stash them (including untracked files) and continue
+	DirtyRefuse DirtyPolicy = "refuse" // fail with a *DirtyError
+)
+
+// Options configures a Preparer.
+type Options struct {
+	Remote  string      // remote to fetch from (default "origin")
+	Dirty   DirtyPolicy // default DirtyStash
+	Exclude []string    // paths ignored when checking for changes (e.g. attachment dirs)
+}
+
+// Preparer brings a repository checkout up to date before a run.
+type Preparer struct {
+	opts   Options
+	logger *slog.Logger
+}
+
+func New(opts Options, logger *slog.Logger) *Preparer {
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	if opts.Dirty == "" {
+		opts.Dirty = DirtyStash
+	}
+	return &Preparer{opts: opts, logger: logger}
+}
+
+// Prepared describes the checkout after Prepare.
+type Prepared struct {
+	Branch  string
+	BaseSHA string // commit the run starts from
+	Stashed string // message of the stash holding leftover changes, if any
+	Backup  string // branch holding unpushed local commits, if any
+}
+
+// DirtyError is returned when the checkout has uncommitted changes and the
+// policy is DirtyRefuse.
+type DirtyError struct {
+	Files []string // porcelain status lines
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("workspace has %d uncommitted change(s)", len(e.Files))
+}
+
+// UnpushedError is returned when the local branch has commits that are not on
+// the remote and the policy is DirtyRefuse.
+type UnpushedError struct {
+	Branch  string
+	Commits []string // one-line logs
+}
+
+func (e *UnpushedError) Error() string {
+	return fmt.Sprintf("branch %s has %d unpushed commit(s)", e.Branch, len(e.Commits))
+}
+
+// Prepare fetches the remote, deals with uncommitted changes and unpushed
+// commits on branch according to the policy and checks out branch at the
+// remote's latest commit. dir may be a
+// subdirectory of the checkout (a monorepo target); the whole checkout is
+// prepared. label names the stash, if one is created.
+func (p *Preparer) Prepare(ctx context.Context, dir, branch, label string) (Prepared, error) {
+	prepared := Prepared{Branch: branch}
+
+	top, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return prepared, err
+	}
+	dir = strings.TrimSpace(top)
+
+	if _, err := git(ctx, dir, "fetch", "--prune", p.opts.Remote); err != nil {
+		return prepared, err
+	}
+
+	status, err := git(ctx, dir, append([]string{"status", "--porcelain"}, p.pathspec()...)...)
+	if err != nil {
+		return prepared, err
+	}
+	if status = strings.TrimRight(status, "\n"); status != "" {
+		files := strings.Split(status, "\n")
+		if p.opts.Dirty == DirtyRefuse {
+			return prepared, &DirtyError{Files: files}
+		}
+		msg := fmt.Sprintf("slack-claude-agent: before %s (%s)", label, time.Now().Format(time.RFC3339))
+		args := append([]string{"stash", "push", "--include-untracked", "-m", msg}, p.pathspec()...)
+		if _, err := git(ctx, dir, args...); err != nil {
+			return prepared, err
+		}
+		p.logger.Warn("stashed leftover changes in workspace", "dir", dir, "files", len(files), "stash", msg)
+		prepared.Stashed = msg
+	}
+
+	backup, err := p.saveUnpushed(ctx, dir, branch)
+	if err != nil {
+		return prepared, err
+	}
+	prepared.Backup = backup
+
+	// -B resets the local branch to the remote one; the tree is clean at this point
+	if _, err := git(ctx, dir, "checkout", "-B", branch, p.opts.Remote+"/"+branch); err != nil {
+		return prepared, err
+	}
+
+	sha, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return prepared, err
+	}
+	prepared.BaseSHA = strings.TrimSpace(sha)
+	return prepared, nil
+}
+
+// saveUnpushed deals with local commits on branch that are not on the remote,
+// which checkout -B would otherwise drop. It returns the backup branch they
+// were saved to, if any.
+func (p *Preparer) saveUnpushed(ctx context.Context, dir, branch string) (string, error) {
+	if _, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		return "", nil // no local branch yet
+	}
+	remote := p.opts.Remote + "/" + branch
+	out, err := git(ctx, dir, "log", "--oneline", remote+".."+branch)
+	if err != nil {
+		return "", err
+	}
+	out = strings.TrimRight(out, "\n")
+	if out == "" {
+		return "", nil
+	}
+	commits := strings.Split(out, "\n")
+	if p.opts.Dirty == DirtyRefuse {
+		return "", &UnpushedError{Branch: branch, Commits: commits}
+	}
+
+	backup := fmt.Sprintf("slack-claude-agent/backup/%s-%s", branch, time.Now().Format("20060102-150405"))
+	if _, err := git(ctx, dir, "branch", backup, branch); err != nil {
+		return "", err
+	}
+	p.logger.Warn("saved unpushed commits to a backup branch", "dir", dir, "branch", branch, "commits", len(commits), "backup", backup)
+	return backup, nil
+}
+
+// pathspec limits status and stash to the repository minus the excluded paths,
+// which are matched in any directory.
+func (p *Preparer) pathspec() []string {
+	if len(p.opts.Exclude) == 0 {
+		return nil
+	}
+	spec := []string{"--", "."}
+	for _, path := range p.opts.Exclude {
+		spec = append(spec, ":(exclude,glob)**/"+path+"/**")
+	}
+	return spec
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// CurrentBranch returns the branch checked out in dir, or "HEAD" if it is detached.
+func CurrentBranch(ctx context.Context, dir string) (string, error) {
+	out, err := git(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Exclude adds pattern to the checkout's .git/info/exclude (unless it is
+// already there), so files matching it are never staged, e.g. by `git add -A`.
+// dir may be a subdirectory of the checkout.
+func Exclude(ctx context.Context, dir, pattern string) error {
+	out, err := git(ctx, dir, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		pattern = "\n" + pattern
+	}
+	if _, err := f.WriteString(pattern + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}